@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -18,11 +22,28 @@ var (
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
+		var exit *exitError
+		if errors.As(err, &exit) {
+			fmt.Fprintln(os.Stderr, exit.message)
+			os.Exit(exit.code)
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+// exitError ends the program with its exit code once the command has
+// returned and its deferred cleanup has run
+type exitError struct {
+	message string
+	code    int
+}
+
+// Error implements error
+func (e *exitError) Error() string {
+	return e.message
+}
+
 var rootCmd = &cobra.Command{
 	Use:     "bullnose [flags] [urls...]",
 	Short:   "A web scraper that converts web pages to clean markdown",
@@ -34,13 +55,32 @@ var rootCmd = &cobra.Command{
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file (default is ./bullnose.yaml)")
 	rootCmd.Flags().StringP("output", "o", "./scraped-content", "output directory for scraped content")
+	rootCmd.Flags().String("output-layout", "title", "output file naming: title, url-path or hash")
+	rootCmd.Flags().Bool("manifest", true, "write manifest.jsonl mapping URLs to output files")
+	rootCmd.Flags().Bool("manifest-csv", false, "also write the manifest as manifest.csv")
+	rootCmd.Flags().String("front-matter", "none", "document header: none, yaml or toml front matter")
+	rootCmd.Flags().Bool("download-images", false, "download images into an assets folder next to the markdown")
+	rootCmd.Flags().Bool("rewrite-links", false, "rewrite links between scraped pages to relative local paths")
+	rootCmd.Flags().Bool("chunks", false, "also write each page as heading-aware chunks in JSON Lines")
+	rootCmd.Flags().Bool("dedup", true, "skip pages whose content duplicates a page saved under another URL")
 	rootCmd.Flags().IntP("depth", "d", 3, "maximum depth to follow links")
 	rootCmd.Flags().IntP("parallel", "p", 8, "number of parallel scraping actions")
+	rootCmd.Flags().Int("max-pages", 0, "stop after requesting this many pages (0 for no limit)")
+	rootCmd.Flags().Int64("max-total-bytes", 0, "stop after downloading this many bytes (0 for no limit)")
+	rootCmd.Flags().String("max-duration", "0s", "stop after crawling for this duration (0s for no limit)")
+	rootCmd.Flags().Int64("max-page-size", 10*1024*1024, "skip pages larger than this many bytes (0 for no limit)")
 	rootCmd.Flags().BoolP("restrict-domain", "r", true, "only follow links within starting domain")
+	rootCmd.Flags().Bool("restrict-path", false, "only follow links under the path of a starting URL")
 	rootCmd.Flags().String("rescrape-after", "12h", "only rescrape after this duration (format: Xm, Xh, Xd)")
 	rootCmd.Flags().BoolP("force", "f", false, "force rescrape regardless of time")
+	rootCmd.Flags().Bool("resume", false, "resume an interrupted crawl from the saved frontier")
+	rootCmd.Flags().Int("max-attempts", 3, "maximum attempts per URL, retrying network errors, 5xx and 429 responses")
 	rootCmd.Flags().Bool("debug", false, "enable debug logging")
+	rootCmd.Flags().StringSlice("include", []string{}, "URL patterns to crawl, globs or regular expressions prefixed with re:")
 	rootCmd.Flags().StringSlice("ignore", []string{}, "URLs or patterns to ignore")
+	rootCmd.Flags().Bool("respect-robots", true, "honor robots.txt, crawl-delay and meta robots directives")
+	rootCmd.Flags().Bool("respect-canonical", true, "save the rel=canonical URL of pages declaring another one instead of the page")
+	rootCmd.Flags().String("user-agent", "bullnose", "user agent sent with requests and matched against robots.txt")
 }
 
 func run(cmd *cobra.Command, args []string) error {
@@ -57,7 +97,9 @@ func run(cmd *cobra.Command, args []string) error {
 
 	// Add command line URLs to config URLs
 	if len(args) > 0 {
-		cfg.URLs = append(cfg.URLs, args...)
+		for _, arg := range args {
+			cfg.URLs = append(cfg.URLs, config.Seed{URL: arg})
+		}
 	}
 
 	// Create and run scraper
@@ -66,7 +108,33 @@ func run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("error creating scraper: %w", err)
 	}
 
-	if err := s.Start(); err != nil {
+	// Stop gracefully on SIGINT/SIGTERM; a second signal exits immediately
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+		case <-ctx.Done():
+			// The crawl finished without a signal
+			return
+		}
+		signal.Stop(signals)
+		fmt.Fprintln(os.Stderr, "\nStopping, press Ctrl-C again to exit immediately")
+		cancel()
+	}()
+
+	if err := s.Start(ctx); err != nil {
+		if errors.Is(err, scraper.ErrStopped) {
+			cmd.SilenceErrors, cmd.SilenceUsage = true, true
+			return &exitError{message: "Scraping stopped early; run again with --resume to continue", code: 130}
+		}
+		if errors.Is(err, scraper.ErrLimitReached) {
+			cmd.SilenceErrors, cmd.SilenceUsage = true, true
+			return &exitError{message: "Scraping stopped at a crawl limit; run again with --resume to continue", code: 3}
+		}
 		return fmt.Errorf("error running scraper: %w", err)
 	}
 
@@ -85,6 +153,38 @@ func loadConfig(cmd *cobra.Command, args []string) (*config.Config, error) {
 		output, _ := cmd.Flags().GetString("output")
 		cfg.Output = output
 	}
+	if cmd.Flags().Changed("output-layout") {
+		outputLayout, _ := cmd.Flags().GetString("output-layout")
+		cfg.OutputLayout = outputLayout
+	}
+	if cmd.Flags().Changed("manifest") {
+		manifest, _ := cmd.Flags().GetBool("manifest")
+		cfg.Manifest = manifest
+	}
+	if cmd.Flags().Changed("manifest-csv") {
+		manifestCSV, _ := cmd.Flags().GetBool("manifest-csv")
+		cfg.ManifestCSV = manifestCSV
+	}
+	if cmd.Flags().Changed("front-matter") {
+		frontMatter, _ := cmd.Flags().GetString("front-matter")
+		cfg.FrontMatter = frontMatter
+	}
+	if cmd.Flags().Changed("download-images") {
+		downloadImages, _ := cmd.Flags().GetBool("download-images")
+		cfg.Images.Download = downloadImages
+	}
+	if cmd.Flags().Changed("rewrite-links") {
+		rewriteLinks, _ := cmd.Flags().GetBool("rewrite-links")
+		cfg.RewriteLinks = rewriteLinks
+	}
+	if cmd.Flags().Changed("chunks") {
+		chunks, _ := cmd.Flags().GetBool("chunks")
+		cfg.Chunks.Enabled = chunks
+	}
+	if cmd.Flags().Changed("dedup") {
+		dedup, _ := cmd.Flags().GetBool("dedup")
+		cfg.Dedup.Enabled = dedup
+	}
 	if cmd.Flags().Changed("depth") {
 		depth, _ := cmd.Flags().GetInt("depth")
 		cfg.Depth = depth
@@ -93,10 +193,34 @@ func loadConfig(cmd *cobra.Command, args []string) (*config.Config, error) {
 		parallel, _ := cmd.Flags().GetInt("parallel")
 		cfg.Parallel = parallel
 	}
+	if cmd.Flags().Changed("max-pages") {
+		maxPages, _ := cmd.Flags().GetInt("max-pages")
+		cfg.MaxPages = maxPages
+	}
+	if cmd.Flags().Changed("max-total-bytes") {
+		maxTotalBytes, _ := cmd.Flags().GetInt64("max-total-bytes")
+		cfg.MaxTotalBytes = maxTotalBytes
+	}
+	if cmd.Flags().Changed("max-duration") {
+		maxDuration, _ := cmd.Flags().GetString("max-duration")
+		duration, err := time.ParseDuration(maxDuration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max-duration: %w", err)
+		}
+		cfg.MaxDuration = duration
+	}
+	if cmd.Flags().Changed("max-page-size") {
+		maxPageSize, _ := cmd.Flags().GetInt64("max-page-size")
+		cfg.MaxPageSize = maxPageSize
+	}
 	if cmd.Flags().Changed("restrict-domain") {
 		restrictDomain, _ := cmd.Flags().GetBool("restrict-domain")
 		cfg.RestrictDomain = restrictDomain
 	}
+	if cmd.Flags().Changed("restrict-path") {
+		restrictPath, _ := cmd.Flags().GetBool("restrict-path")
+		cfg.RestrictPath = restrictPath
+	}
 	if cmd.Flags().Changed("rescrape-after") {
 		rescrapeAfter, _ := cmd.Flags().GetString("rescrape-after")
 		duration, err := time.ParseDuration(rescrapeAfter)
@@ -109,14 +233,38 @@ func loadConfig(cmd *cobra.Command, args []string) (*config.Config, error) {
 		force, _ := cmd.Flags().GetBool("force")
 		cfg.Force = force
 	}
+	if cmd.Flags().Changed("resume") {
+		resume, _ := cmd.Flags().GetBool("resume")
+		cfg.Resume = resume
+	}
+	if cmd.Flags().Changed("max-attempts") {
+		maxAttempts, _ := cmd.Flags().GetInt("max-attempts")
+		cfg.Retry.MaxAttempts = maxAttempts
+	}
 	if cmd.Flags().Changed("debug") {
 		debug, _ := cmd.Flags().GetBool("debug")
 		cfg.Debug = debug
 	}
+	if cmd.Flags().Changed("include") {
+		include, _ := cmd.Flags().GetStringSlice("include")
+		cfg.Include = include
+	}
 	if cmd.Flags().Changed("ignore") {
 		ignore, _ := cmd.Flags().GetStringSlice("ignore")
 		cfg.Ignore = ignore
 	}
+	if cmd.Flags().Changed("respect-robots") {
+		respectRobots, _ := cmd.Flags().GetBool("respect-robots")
+		cfg.RespectRobots = respectRobots
+	}
+	if cmd.Flags().Changed("respect-canonical") {
+		respectCanonical, _ := cmd.Flags().GetBool("respect-canonical")
+		cfg.RespectCanonical = respectCanonical
+	}
+	if cmd.Flags().Changed("user-agent") {
+		userAgent, _ := cmd.Flags().GetString("user-agent")
+		cfg.UserAgent = userAgent
+	}
 
 	return cfg, nil
 }
@@ -2,26 +2,75 @@ package config
 
 import (
 	"fmt"
+	"path"
 	"path/filepath"
 	"regexp"
+	"strings"
+	"text/template"
 	"time"
 
+	"github.com/andybalholm/cascadia"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
 // LoadConfig loads configuration from file and environment variables
 func LoadConfig(configFile string) (*Config, error) {
-	v := viper.New()
+	// Domain names contain dots, so use a key delimiter that cannot clash
+	v := viper.NewWithOptions(viper.KeyDelimiter("::"))
 
 	// Set default values
 	v.SetDefault("output", "./scraped-content")
+	v.SetDefault("output-layout", "title")
+	v.SetDefault("manifest", true)
+	v.SetDefault("manifest-csv", false)
+	v.SetDefault("links-section", false)
+	v.SetDefault("front-matter", "none")
+	v.SetDefault("front-matter-fields", map[string]string{})
+	v.SetDefault("header-template", "")
+	v.SetDefault("images::download", false)
+	v.SetDefault("images::max-size", 5*1024*1024)
+	v.SetDefault("rewrite-links", false)
+	v.SetDefault("chunks::enabled", false)
+	v.SetDefault("chunks::size", 500)
+	v.SetDefault("chunks::overlap", 50)
+	v.SetDefault("chunks::unit", "tokens")
+	v.SetDefault("chunks::markdown", true)
+	v.SetDefault("dedup::enabled", true)
+	v.SetDefault("dedup::near-duplicates", true)
+	v.SetDefault("dedup::max-distance", 3)
+	v.SetDefault("normalize::drop-params", []string{
+		"utm_*", "fbclid", "gclid", "dclid", "msclkid", "yclid", "mc_cid", "mc_eid", "_hsenc", "_hsmi",
+	})
+	v.SetDefault("normalize::sort-query", true)
+	v.SetDefault("normalize::ignore-scheme", true)
+	v.SetDefault("normalize::ignore-trailing-slash", true)
+	v.SetDefault("respect-canonical", true)
 	v.SetDefault("depth", 3)
+	v.SetDefault("max-pages", 0)
+	v.SetDefault("max-total-bytes", 0)
+	v.SetDefault("max-duration", "0s")
+	v.SetDefault("max-page-size", 10*1024*1024)
 	v.SetDefault("parallel", 8)
 	v.SetDefault("restrict-domain", true)
+	v.SetDefault("restrict-path", false)
 	v.SetDefault("rescrape-after", "12h")
 	v.SetDefault("force", false)
+	v.SetDefault("resume", false)
+	v.SetDefault("checkpoint-interval", "30s")
+	v.SetDefault("shutdown-timeout", "10s")
+	v.SetDefault("retry::max-attempts", 3)
+	v.SetDefault("retry::initial-backoff", "1s")
+	v.SetDefault("retry::max-backoff", "30s")
+	v.SetDefault("retry::max-retry-after", "5m")
+	v.SetDefault("throttle::enabled", true)
+	v.SetDefault("throttle::max-delay", "30s")
+	v.SetDefault("throttle::recover-after", 20)
 	v.SetDefault("debug", false)
 	v.SetDefault("parse-sitemaps", true)
+	v.SetDefault("respect-robots", true)
+	v.SetDefault("user-agent", "bullnose")
+	v.SetDefault("include", []string{})
 	v.SetDefault("ignore", []string{
 		"login",
 		"admin",
@@ -56,7 +105,12 @@ func LoadConfig(configFile string) (*Config, error) {
 	}
 
 	var config Config
-	if err := v.Unmarshal(&config); err != nil {
+	decodeHook := viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		stringToSeedHook,
+	))
+	if err := v.Unmarshal(&config, decodeHook); err != nil {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
@@ -77,6 +131,15 @@ func LoadConfig(configFile string) (*Config, error) {
 		}
 		config.Output = absPath
 	}
+	for i, seed := range config.URLs {
+		if seed.Output != "" && !filepath.IsAbs(seed.Output) {
+			absPath, err := filepath.Abs(seed.Output)
+			if err != nil {
+				return nil, fmt.Errorf("error converting output path of %s to absolute: %w", seed.URL, err)
+			}
+			config.URLs[i].Output = absPath
+		}
+	}
 
 	// Validate the configuration
 	if err := validateConfig(&config); err != nil {
@@ -95,12 +158,138 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("parallel must be greater than 0")
 	}
 
+	if config.MaxPages < 0 || config.MaxTotalBytes < 0 || config.MaxDuration < 0 || config.MaxPageSize < 0 {
+		return fmt.Errorf("max-pages, max-total-bytes, max-duration and max-page-size must be non-negative")
+	}
+
 	if config.RescrapeAfter < 0 {
 		return fmt.Errorf("rescrape-after must be non-negative")
 	}
 
-	// Validate regex patterns
+	if config.CheckpointInterval <= 0 {
+		return fmt.Errorf("checkpoint-interval must be greater than 0")
+	}
+
+	if config.ShutdownTimeout < 0 {
+		return fmt.Errorf("shutdown-timeout must be non-negative")
+	}
+
+	if err := validateRetry(config.Retry); err != nil {
+		return err
+	}
+
+	if config.Throttle.Enabled {
+		if config.Throttle.MaxDelay <= 0 {
+			return fmt.Errorf("throttle max-delay must be greater than 0")
+		}
+		if config.Throttle.RecoverAfter < 1 {
+			return fmt.Errorf("throttle recover-after must be greater than 0")
+		}
+	}
+
+	if config.Images.Download && config.Images.MaxSize < 1 {
+		return fmt.Errorf("images max-size must be greater than 0")
+	}
+
+	if config.Chunks.Enabled {
+		if config.Chunks.Size < 1 {
+			return fmt.Errorf("chunks size must be greater than 0")
+		}
+		if config.Chunks.Overlap < 0 || config.Chunks.Overlap >= config.Chunks.Size {
+			return fmt.Errorf("chunks overlap must be non-negative and smaller than size")
+		}
+		switch config.Chunks.Unit {
+		case "chars", "tokens":
+		default:
+			return fmt.Errorf("chunks unit must be one of chars or tokens")
+		}
+		if !config.Chunks.Markdown && config.RewriteLinks {
+			return fmt.Errorf("rewrite-links cannot be used when chunks markdown is false")
+		}
+	}
+
+	if config.Dedup.MaxDistance < 0 || config.Dedup.MaxDistance > 64 {
+		return fmt.Errorf("dedup max-distance must be between 0 and 64")
+	}
+
+	switch config.OutputLayout {
+	case "title", "url-path", "hash":
+	default:
+		return fmt.Errorf("output-layout must be one of title, url-path or hash")
+	}
+
+	switch config.FrontMatter {
+	case "none", "yaml", "toml":
+	default:
+		return fmt.Errorf("front-matter must be one of none, yaml or toml")
+	}
+
+	if config.HeaderTemplate != "" {
+		if _, err := template.New("header").Parse(config.HeaderTemplate); err != nil {
+			return fmt.Errorf("invalid header-template: %w", err)
+		}
+	}
+
+	if config.UserAgent == "" {
+		return fmt.Errorf("user-agent must not be empty")
+	}
+
+	if err := validateInclude(config.Include); err != nil {
+		return err
+	}
+
+	// Validate per-seed settings
+	for _, seed := range config.URLs {
+		if seed.URL == "" {
+			return fmt.Errorf("every entry of urls must have a url")
+		}
+		if seed.Depth < 0 {
+			return fmt.Errorf("depth for %s must be non-negative", seed.URL)
+		}
+		if err := validateInclude(seed.Include); err != nil {
+			return fmt.Errorf("%s: %w", seed.URL, err)
+		}
+		if _, ok := config.ContentPatterns[seed.ExtractionProfile]; seed.ExtractionProfile != "" && !ok {
+			return fmt.Errorf("extraction-profile %s for %s is not defined in content-patterns", seed.ExtractionProfile, seed.URL)
+		}
+	}
+
+	// Validate domain patterns and rate limits
+	for domain, domainCfg := range config.DomainConfig {
+		if _, err := path.Match(domain, ""); err != nil {
+			return fmt.Errorf("invalid domain pattern %s: %w", domain, err)
+		}
+		if domainCfg == nil {
+			continue
+		}
+		if domainCfg.Parallelism < 0 {
+			return fmt.Errorf("parallelism for domain %s must be non-negative", domain)
+		}
+		if domainCfg.Delay < 0 || (domainCfg.RandomDelay != nil && *domainCfg.RandomDelay < 0) {
+			return fmt.Errorf("delays for domain %s must be non-negative", domain)
+		}
+		if domainCfg.RequestsPerSecond < 0 {
+			return fmt.Errorf("requests-per-second for domain %s must be non-negative", domain)
+		}
+		if domainCfg.MaxPages < 0 {
+			return fmt.Errorf("max-pages for domain %s must be non-negative", domain)
+		}
+		if domainCfg.Retry.MaxAttempts < 0 || domainCfg.Retry.InitialBackoff < 0 || domainCfg.Retry.MaxBackoff < 0 ||
+			domainCfg.Retry.MaxRetryAfter < 0 {
+			return fmt.Errorf("retry settings for domain %s must be non-negative", domain)
+		}
+		if err := validateRetry(config.Retry.Merge(domainCfg.Retry)); err != nil {
+			return fmt.Errorf("domain %s: %w", domain, err)
+		}
+	}
+
+	// Validate content extraction rules
 	for domain, extraction := range config.ContentPatterns {
+		switch extraction.Strategy {
+		case "", "auto", "readability", "semantic", "body":
+		default:
+			return fmt.Errorf("strategy for domain %s must be one of auto, readability, semantic or body", domain)
+		}
 		if extraction.TitlePattern != "" {
 			if _, err := regexp.Compile(extraction.TitlePattern); err != nil {
 				return fmt.Errorf("invalid title pattern for domain %s: %w", domain, err)
@@ -116,6 +305,55 @@ func validateConfig(config *Config) error {
 				return fmt.Errorf("invalid exclude pattern for domain %s: %w", domain, err)
 			}
 		}
+
+		// Validate CSS selectors
+		if extraction.TitleSelector != "" {
+			if _, err := cascadia.Compile(extraction.TitleSelector); err != nil {
+				return fmt.Errorf("invalid title selector for domain %s: %w", domain, err)
+			}
+		}
+		for _, selector := range extraction.ContentSelectors {
+			if _, err := cascadia.Compile(selector); err != nil {
+				return fmt.Errorf("invalid content selector for domain %s: %w", domain, err)
+			}
+		}
+		for _, selector := range extraction.ExcludeSelectors {
+			if _, err := cascadia.Compile(selector); err != nil {
+				return fmt.Errorf("invalid exclude selector for domain %s: %w", domain, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateInclude checks the include patterns given as regular expressions
+func validateInclude(patterns []string) error {
+	for _, pattern := range patterns {
+		if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("invalid include pattern %s: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateRetry(retry RetryConfig) error {
+	if retry.MaxAttempts < 1 {
+		return fmt.Errorf("retry max-attempts must be greater than 0")
+	}
+
+	if retry.InitialBackoff <= 0 {
+		return fmt.Errorf("retry initial-backoff must be greater than 0")
+	}
+
+	if retry.MaxBackoff < retry.InitialBackoff {
+		return fmt.Errorf("retry max-backoff must not be less than initial-backoff")
+	}
+
+	if retry.MaxRetryAfter < retry.MaxBackoff {
+		return fmt.Errorf("retry max-retry-after must not be less than max-backoff")
 	}
 
 	return nil
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"net"
+	"path"
+	"strings"
+)
+
+// HasLimits reports whether the domain overrides the global rate limits
+func (d *DomainConfig) HasLimits() bool {
+	return d.Parallelism > 0 || d.Delay > 0 || d.RandomDelay != nil || d.RequestsPerSecond > 0
+}
+
+// Merge returns the retry settings with the non-zero values of override applied
+func (r RetryConfig) Merge(override RetryConfig) RetryConfig {
+	if override.MaxAttempts > 0 {
+		r.MaxAttempts = override.MaxAttempts
+	}
+	if override.InitialBackoff > 0 {
+		r.InitialBackoff = override.InitialBackoff
+	}
+	if override.MaxBackoff > 0 {
+		r.MaxBackoff = override.MaxBackoff
+	}
+	if override.MaxRetryAfter > 0 {
+		r.MaxRetryAfter = override.MaxRetryAfter
+	}
+	return r
+}
+
+// RetryPolicy returns the retry settings for a host, applying the overrides
+// of its domain-config entry to the global settings
+func (c *Config) RetryPolicy(host string) RetryConfig {
+	if _, domainCfg := c.Domain(host); domainCfg != nil {
+		return c.Retry.Merge(domainCfg.Retry)
+	}
+	return c.Retry
+}
+
+// Domain returns the domain-config entry that applies to a host along with
+// its key. An exact match wins over glob patterns, and among matching
+// patterns the longest, most specific one is used. The port is ignored
+// unless the key includes one.
+func (c *Config) Domain(host string) (string, *DomainConfig) {
+	host = strings.ToLower(host)
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+
+	for _, name := range []string{host, hostname} {
+		for key, domainCfg := range c.DomainConfig {
+			if domainCfg != nil && strings.ToLower(key) == name {
+				return key, domainCfg
+			}
+		}
+	}
+
+	var (
+		bestKey string
+		best    *DomainConfig
+	)
+	for key, domainCfg := range c.DomainConfig {
+		if domainCfg == nil || !matchHost(strings.ToLower(key), host, hostname) {
+			continue
+		}
+		if best == nil || len(key) > len(bestKey) || (len(key) == len(bestKey) && key < bestKey) {
+			bestKey, best = key, domainCfg
+		}
+	}
+	return bestKey, best
+}
+
+// matchHost reports whether a domain glob matches a host with or without its port
+func matchHost(pattern, host, hostname string) bool {
+	if ok, _ := path.Match(pattern, host); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, hostname)
+	return ok
+}
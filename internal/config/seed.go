@@ -0,0 +1,47 @@
+package config
+
+import "reflect"
+
+// seedType is the type plain URLs in the urls list are decoded into
+var seedType = reflect.TypeOf(Seed{})
+
+// stringToSeedHook decodes a plain URL in the urls list as a seed using the
+// global settings
+func stringToSeedHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != seedType {
+		return data, nil
+	}
+	return map[string]interface{}{"url": data}, nil
+}
+
+// ResolveSeed returns the settings of a seed with the global settings in
+// place of the ones it leaves unset
+func (c *Config) ResolveSeed(seed Seed) Seed {
+	if seed.Depth == 0 {
+		seed.Depth = c.Depth
+	}
+	if seed.Include == nil {
+		seed.Include = c.Include
+	}
+	if seed.Ignore == nil {
+		seed.Ignore = c.Ignore
+	}
+	if seed.Output == "" {
+		seed.Output = c.Output
+	}
+	if seed.ParseSitemaps == nil {
+		seed.ParseSitemaps = boolPtr(c.ParseSitemaps)
+	}
+	if seed.RestrictDomain == nil {
+		seed.RestrictDomain = boolPtr(c.RestrictDomain)
+	}
+	if seed.RestrictPath == nil {
+		seed.RestrictPath = boolPtr(c.RestrictPath)
+	}
+	return seed
+}
+
+// boolPtr returns a pointer to a copy of b
+func boolPtr(b bool) *bool {
+	return &b
+}
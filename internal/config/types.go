@@ -2,10 +2,66 @@ package config
 
 import "time"
 
-// DomainConfig holds domain-specific configuration
+// DomainConfig holds domain-specific configuration. Keys of the domain-config
+// map are host names or glob patterns such as *.example.com.
 type DomainConfig struct {
-	Headers map[string]string `mapstructure:"headers"`
-	Cookies map[string]string `mapstructure:"cookies"`
+	Headers           map[string]string `mapstructure:"headers"`
+	Cookies           map[string]string `mapstructure:"cookies"`
+	Parallelism       int               `mapstructure:"parallelism"`
+	Delay             time.Duration     `mapstructure:"delay"`
+	RandomDelay       *time.Duration    `mapstructure:"random-delay"` // nil uses the global random delay
+	RequestsPerSecond float64           `mapstructure:"requests-per-second"`
+	MaxPages          int               `mapstructure:"max-pages"` // 0 for no limit
+	Retry             RetryConfig       `mapstructure:"retry"`     // unset values use the global retry settings
+}
+
+// RetryConfig holds the retry policy for transient failures
+type RetryConfig struct {
+	MaxAttempts    int           `mapstructure:"max-attempts"`
+	InitialBackoff time.Duration `mapstructure:"initial-backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max-backoff"`
+	MaxRetryAfter  time.Duration `mapstructure:"max-retry-after"` // longest Retry-After honored
+}
+
+// ThrottleConfig holds the settings of the adaptive throttle that slows down
+// hosts answering 429 or 503
+type ThrottleConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	MaxDelay     time.Duration `mapstructure:"max-delay"`
+	RecoverAfter int           `mapstructure:"recover-after"`
+}
+
+// ImagesConfig holds the settings for downloading the images of pages
+type ImagesConfig struct {
+	Download bool  `mapstructure:"download"`
+	MaxSize  int64 `mapstructure:"max-size"` // in bytes
+}
+
+// ChunksConfig holds the settings for writing pages as chunks for
+// embedding pipelines
+type ChunksConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Size     int    `mapstructure:"size"`
+	Overlap  int    `mapstructure:"overlap"`
+	Unit     string `mapstructure:"unit"`     // chars or tokens
+	Markdown bool   `mapstructure:"markdown"` // also write the .md files
+}
+
+// DedupConfig holds the settings for skipping pages whose content was
+// already saved under another URL
+type DedupConfig struct {
+	Enabled        bool `mapstructure:"enabled"`
+	NearDuplicates bool `mapstructure:"near-duplicates"`
+	MaxDistance    int  `mapstructure:"max-distance"` // differing SimHash bits, out of 64
+}
+
+// NormalizeConfig holds the settings for recognizing different spellings
+// of the same URL
+type NormalizeConfig struct {
+	DropParams          []string `mapstructure:"drop-params"` // glob patterns of query parameter names
+	SortQuery           bool     `mapstructure:"sort-query"`
+	IgnoreScheme        bool     `mapstructure:"ignore-scheme"`
+	IgnoreTrailingSlash bool     `mapstructure:"ignore-trailing-slash"`
 }
 
 // ContentExtraction holds configuration for content extraction
@@ -13,20 +69,66 @@ type ContentExtraction struct {
 	TitlePattern    string   `mapstructure:"title-pattern"`
 	ContentPatterns []string `mapstructure:"content-patterns"`
 	ExcludePatterns []string `mapstructure:"exclude-patterns"`
+	Strategy        string   `mapstructure:"strategy"`
+
+	TitleSelector    string   `mapstructure:"title-selector"`
+	ContentSelectors []string `mapstructure:"content-selectors"`
+	ExcludeSelectors []string `mapstructure:"exclude-selectors"`
+}
+
+// Seed is a starting URL of the crawl. Its settings apply to every page
+// discovered from it, and unset settings use the global ones. Entries of the
+// urls list may also be plain URLs.
+type Seed struct {
+	URL               string   `mapstructure:"url"`
+	Depth             int      `mapstructure:"depth"`
+	Include           []string `mapstructure:"include"` // nil uses the global include patterns
+	Ignore            []string `mapstructure:"ignore"`  // nil uses the global ignore patterns
+	Output            string   `mapstructure:"output"`
+	ParseSitemaps     *bool    `mapstructure:"parse-sitemaps"`
+	RestrictDomain    *bool    `mapstructure:"restrict-domain"`
+	RestrictPath      *bool    `mapstructure:"restrict-path"`
+	ExtractionProfile string   `mapstructure:"extraction-profile"` // content-patterns entry to extract with
 }
 
 // Config holds all configuration for the scraper
 type Config struct {
-	Output          string                       `mapstructure:"output"`
-	Depth           int                          `mapstructure:"depth"`
-	Parallel        int                          `mapstructure:"parallel"`
-	RestrictDomain  bool                         `mapstructure:"restrict-domain"`
-	RescrapeAfter   time.Duration                `mapstructure:"rescrape-after"`
-	Force           bool                         `mapstructure:"force"`
-	Debug           bool                         `mapstructure:"debug"`
-	Ignore          []string                     `mapstructure:"ignore"`
-	URLs            []string                     `mapstructure:"urls"`
-	ParseSitemaps   bool                         `mapstructure:"parse-sitemaps"`
-	DomainConfig    map[string]*DomainConfig     `mapstructure:"domain-config"`
-	ContentPatterns map[string]ContentExtraction `mapstructure:"content-patterns"`
+	Output             string                       `mapstructure:"output"`
+	OutputLayout       string                       `mapstructure:"output-layout"`
+	Manifest           bool                         `mapstructure:"manifest"`
+	ManifestCSV        bool                         `mapstructure:"manifest-csv"`
+	LinksSection       bool                         `mapstructure:"links-section"`
+	FrontMatter        string                       `mapstructure:"front-matter"`
+	FrontMatterFields  map[string]string            `mapstructure:"front-matter-fields"`
+	HeaderTemplate     string                       `mapstructure:"header-template"`
+	Images             ImagesConfig                 `mapstructure:"images"`
+	RewriteLinks       bool                         `mapstructure:"rewrite-links"`
+	Chunks             ChunksConfig                 `mapstructure:"chunks"`
+	Dedup              DedupConfig                  `mapstructure:"dedup"`
+	Normalize          NormalizeConfig              `mapstructure:"normalize"`
+	RespectCanonical   bool                         `mapstructure:"respect-canonical"`
+	Depth              int                          `mapstructure:"depth"`
+	MaxPages           int                          `mapstructure:"max-pages"`       // 0 for no limit
+	MaxTotalBytes      int64                        `mapstructure:"max-total-bytes"` // 0 for no limit
+	MaxDuration        time.Duration                `mapstructure:"max-duration"`    // 0 for no limit
+	MaxPageSize        int64                        `mapstructure:"max-page-size"`   // in bytes, 0 for no limit
+	Parallel           int                          `mapstructure:"parallel"`
+	RestrictDomain     bool                         `mapstructure:"restrict-domain"`
+	RestrictPath       bool                         `mapstructure:"restrict-path"`
+	RescrapeAfter      time.Duration                `mapstructure:"rescrape-after"`
+	Force              bool                         `mapstructure:"force"`
+	Resume             bool                         `mapstructure:"resume"`
+	CheckpointInterval time.Duration                `mapstructure:"checkpoint-interval"`
+	ShutdownTimeout    time.Duration                `mapstructure:"shutdown-timeout"`
+	Retry              RetryConfig                  `mapstructure:"retry"`
+	Throttle           ThrottleConfig               `mapstructure:"throttle"`
+	Debug              bool                         `mapstructure:"debug"`
+	RespectRobots      bool                         `mapstructure:"respect-robots"`
+	UserAgent          string                       `mapstructure:"user-agent"`
+	Include            []string                     `mapstructure:"include"`
+	Ignore             []string                     `mapstructure:"ignore"`
+	URLs               []Seed                       `mapstructure:"urls"`
+	ParseSitemaps      bool                         `mapstructure:"parse-sitemaps"`
+	DomainConfig       map[string]*DomainConfig     `mapstructure:"domain-config"`
+	ContentPatterns    map[string]ContentExtraction `mapstructure:"content-patterns"`
 }
@@ -0,0 +1,50 @@
+package scraper
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// throttleTransport passes every request through the adaptive throttle so
+// hosts that answer 429 or 503 are crawled more gently
+type throttleTransport struct {
+	base    http.RoundTripper
+	scraper *Scraper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *throttleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s := t.scraper
+	host := req.URL.Host
+	if err := s.throttle.Acquire(s.ctx, host); err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+
+	before := s.throttle.State(host)
+	slowed := s.throttle.Release(host, started, statusCode)
+	state := s.throttle.State(host)
+	if slowed {
+		s.stats.IncrementThrottled()
+		log.Printf("%s is throttling requests (%d), slowing down to parallelism %d with a %s delay",
+			host, statusCode, state.Parallelism, state.Delay)
+	} else if s.config.Debug && state != before {
+		log.Printf("%s recovering: parallelism %d with a %s delay", host, state.Parallelism, state.Delay)
+	}
+	return resp, err
+}
+
+// maxParallel returns the parallelism the limit rules allow for a host
+func (s *Scraper) maxParallel(host string) int {
+	if limit, ok := s.domainLimit(host); ok {
+		return limit.rule.Parallelism
+	}
+	return s.config.Parallel
+}
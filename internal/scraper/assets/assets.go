@@ -0,0 +1,123 @@
+package assets
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// imageExtensions maps image content types to file extensions
+var imageExtensions = map[string]string{
+	"image/avif":    ".avif",
+	"image/bmp":     ".bmp",
+	"image/gif":     ".gif",
+	"image/jpeg":    ".jpg",
+	"image/png":     ".png",
+	"image/svg+xml": ".svg",
+	"image/tiff":    ".tiff",
+	"image/webp":    ".webp",
+	"image/x-icon":  ".ico",
+}
+
+// Downloader fetches the images referenced by pages. URLs that failed are
+// remembered so they are not requested again for every page using them.
+type Downloader struct {
+	client  *http.Client
+	maxSize int64
+	prepare func(*http.Request) error
+	failed  map[string]error
+	mutex   sync.Mutex
+}
+
+// NewDownloader creates a downloader sending requests through transport.
+// Images larger than maxSize bytes are refused. prepare is called before
+// every request to apply the crawl's rules, and its error cancels the
+// download.
+func NewDownloader(transport http.RoundTripper, maxSize int64, prepare func(*http.Request) error) *Downloader {
+	return &Downloader{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
+		},
+		maxSize: maxSize,
+		prepare: prepare,
+		failed:  make(map[string]error),
+	}
+}
+
+// Fetch downloads an image and returns its content and file extension
+func (d *Downloader) Fetch(ctx context.Context, imageURL string) ([]byte, string, error) {
+	d.mutex.Lock()
+	err, failed := d.failed[imageURL]
+	d.mutex.Unlock()
+	if failed {
+		return nil, "", err
+	}
+
+	data, ext, err := d.fetch(ctx, imageURL)
+	if err != nil && ctx.Err() == nil {
+		d.mutex.Lock()
+		d.failed[imageURL] = err
+		d.mutex.Unlock()
+	}
+	return data, ext, err
+}
+
+// fetch performs the download of an image
+func (d *Downloader) fetch(ctx context.Context, imageURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid image URL: %w", err)
+	}
+	if err := d.prepare(req); err != nil {
+		return nil, "", err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > d.maxSize {
+		return nil, "", fmt.Errorf("image is larger than %d bytes", d.maxSize)
+	}
+
+	ext, err := extension(resp.Header.Get("Content-Type"), req.URL.Path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Read one byte more than allowed to detect oversized bodies
+	data, err := io.ReadAll(io.LimitReader(resp.Body, d.maxSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image: %w", err)
+	}
+	if int64(len(data)) > d.maxSize {
+		return nil, "", fmt.Errorf("image is larger than %d bytes", d.maxSize)
+	}
+
+	return data, ext, nil
+}
+
+// extension returns the file extension for an image, refusing responses
+// that are not images such as error pages
+func extension(contentType, urlPath string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "image/") {
+		return "", fmt.Errorf("not an image: %q", contentType)
+	}
+	if ext, ok := imageExtensions[mediaType]; ok {
+		return ext, nil
+	}
+	return strings.ToLower(path.Ext(urlPath)), nil
+}
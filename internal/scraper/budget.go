@@ -0,0 +1,171 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrLimitReached is returned by Start when the crawl stopped at one of its
+// limits, or left the pages of a domain over its page limit uncrawled
+var ErrLimitReached = errors.New("crawl limit reached")
+
+// errPageTooLarge is the error of requests for pages over max-page-size
+var errPageTooLarge = errors.New("page larger than max-page-size")
+
+// limitEffect is what reaching a limit does to the rest of the crawl
+type limitEffect int
+
+const (
+	// skipPages leaves the pages the limit applies to pending
+	skipPages limitEffect = iota
+	// drain starts no new pages but lets the pages already taken finish
+	drain
+	// halt also ends pending waits for rate limits, retries and throttled hosts
+	halt
+)
+
+// budget tracks the crawl against its limits on pages, bytes and time
+type budget struct {
+	mutex       sync.Mutex
+	pages       int
+	domainPages map[string]int     // domain-config key -> pages requested
+	reached     []string           // limits reached, first one first
+	draining    atomic.Bool        // whether new pages are refused
+	cancel      context.CancelFunc // cancels the crawl context
+}
+
+// takePage counts a page request against max-pages and the max-pages of the
+// domain-config entry of its host. It reports false when a limit leaves no room
+// for the page, which then stays pending for --resume. A page refused by
+// max-pages drains the crawl, so the pages already taken are still saved.
+func (s *Scraper) takePage(u *url.URL) bool {
+	key, domainCfg := s.config.Domain(u.Host)
+	domainMax := 0
+	if domainCfg != nil {
+		domainMax = domainCfg.MaxPages
+	}
+
+	s.budget.mutex.Lock()
+	if s.budget.domainPages == nil {
+		s.budget.domainPages = make(map[string]int)
+	}
+	if s.config.MaxPages > 0 && s.budget.pages >= s.config.MaxPages {
+		s.budget.mutex.Unlock()
+		s.reachLimit("max-pages", drain)
+		return false
+	}
+	if domainMax > 0 && s.budget.domainPages[key] >= domainMax {
+		s.budget.mutex.Unlock()
+		if s.config.Debug {
+			log.Printf("Not requesting %s (max-pages for %s reached)", u, key)
+		}
+		s.reachLimit(fmt.Sprintf("max-pages for %s", key), skipPages)
+		return false
+	}
+	s.budget.pages++
+	s.budget.domainPages[key]++
+	s.budget.mutex.Unlock()
+	return true
+}
+
+// releasePage gives back a page taken by takePage whose request was not made
+func (s *Scraper) releasePage(u *url.URL) {
+	key, _ := s.config.Domain(u.Host)
+	s.budget.mutex.Lock()
+	defer s.budget.mutex.Unlock()
+	s.budget.pages--
+	s.budget.domainPages[key]--
+}
+
+// addBytes counts downloaded bytes against max-total-bytes, stopping the
+// crawl once they are used up
+func (s *Scraper) addBytes(n int) {
+	total := s.stats.AddBytes(int64(n))
+	if s.config.MaxTotalBytes > 0 && total >= s.config.MaxTotalBytes {
+		s.reachLimit("max-total-bytes", halt)
+	}
+}
+
+// reachLimit records a limit the crawl reached and applies its effect. The
+// crawl ends once the requests it lets finish are done.
+func (s *Scraper) reachLimit(reason string, effect limitEffect) {
+	s.budget.mutex.Lock()
+	first := !contains(s.budget.reached, reason)
+	if first {
+		s.budget.reached = append(s.budget.reached, reason)
+	}
+	s.budget.mutex.Unlock()
+	if !first {
+		return
+	}
+	s.stats.Limit(reason)
+
+	switch effect {
+	case skipPages:
+		log.Printf("Reached %s, leaving its remaining pages for --resume", reason)
+	case drain:
+		log.Printf("Reached %s, finishing the pages already started", reason)
+		s.budget.draining.Store(true)
+	case halt:
+		log.Printf("Reached %s, finishing in-flight requests", reason)
+		s.budget.draining.Store(true)
+		if s.budget.cancel != nil {
+			s.budget.cancel()
+		}
+	}
+}
+
+// limitReached returns the first limit the crawl reached, if any
+func (s *Scraper) limitReached() string {
+	s.budget.mutex.Lock()
+	defer s.budget.mutex.Unlock()
+	if len(s.budget.reached) == 0 {
+		return ""
+	}
+	return s.budget.reached[0]
+}
+
+// pageSizeTransport fails responses with a body larger than maxSize, so
+// oversized pages are skipped rather than truncated
+type pageSizeTransport struct {
+	base    http.RoundTripper
+	maxSize int64
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *pageSizeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if resp.ContentLength > t.maxSize {
+		resp.Body.Close()
+		return nil, errPageTooLarge
+	}
+	resp.Body = &cappedBody{ReadCloser: resp.Body, remaining: t.maxSize}
+	return resp, nil
+}
+
+// cappedBody is a response body that fails once more than its remaining
+// bytes are read
+type cappedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+// Read implements io.Reader
+func (b *cappedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n, errPageTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,214 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/bullnose/internal/config"
+)
+
+func TestTakePage(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxPages  int
+		domainMax int
+		take      []string
+		want      []bool
+		reached   string
+		draining  bool
+	}{
+		{
+			name: "no limits",
+			take: []string{"https://example.com/a", "https://docs.example.com/a", "https://docs.example.com/b"},
+			want: []bool{true, true, true},
+		},
+		{
+			name:     "max-pages",
+			maxPages: 2,
+			take:     []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"},
+			want:     []bool{true, true, false},
+			reached:  "max-pages",
+			draining: true,
+		},
+		{
+			name:      "domain max-pages",
+			domainMax: 1,
+			take:      []string{"https://docs.example.com/a", "https://docs.example.com/b", "https://example.com/a", "https://docs.example.com/c"},
+			want:      []bool{true, false, true, false},
+			reached:   "max-pages for docs.example.com",
+		},
+		{
+			name:      "domain max-pages before max-pages",
+			maxPages:  2,
+			domainMax: 1,
+			take:      []string{"https://docs.example.com/a", "https://docs.example.com/b", "https://example.com/a", "https://example.com/b"},
+			want:      []bool{true, false, true, false},
+			reached:   "max-pages for docs.example.com",
+			draining:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := loadTestConfig(t, fmt.Sprintf("urls:\n  - https://example.com/\noutput: %s\nmax-pages: %d\ndomain-config:\n  docs.example.com:\n    max-pages: %d\n",
+				t.TempDir(), tt.maxPages, tt.domainMax))
+			s, err := New(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, rawURL := range tt.take {
+				u, err := url.Parse(rawURL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := s.takePage(u); got != tt.want[i] {
+					t.Errorf("takePage(%s) = %v, want %v", u, got, tt.want[i])
+				}
+			}
+			if got := s.limitReached(); got != tt.reached {
+				t.Errorf("limitReached() = %q, want %q", got, tt.reached)
+			}
+			if got := s.budget.draining.Load(); got != tt.draining {
+				t.Errorf("draining = %v, want %v", got, tt.draining)
+			}
+		})
+	}
+}
+
+func TestReleasePage(t *testing.T) {
+	cfg := loadTestConfig(t, fmt.Sprintf("urls:\n  - https://example.com/\noutput: %s\nmax-pages: 1\n", t.TempDir()))
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse("https://example.com/a")
+	if !s.takePage(u) {
+		t.Fatal("takePage() = false for the first page")
+	}
+	// A page given back before its request was made leaves room for another
+	s.releasePage(u)
+	if !s.takePage(u) {
+		t.Error("takePage() = false after the page was released")
+	}
+	if s.limitReached() != "" {
+		t.Errorf("limitReached() = %q after a released page", s.limitReached())
+	}
+}
+
+func TestPageSizeTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := strings.Repeat("x", 100)
+		if r.URL.Query().Get("length") == "" {
+			// Without a Content-Length the size is only known while reading
+			w.Header().Set("Transfer-Encoding", "chunked")
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		maxSize int64
+		query   string
+		wantErr bool
+	}{
+		{name: "within the limit", maxSize: 100, query: "?length=1"},
+		{name: "over the limit", maxSize: 99, query: "?length=1", wantErr: true},
+		{name: "streamed within the limit", maxSize: 100},
+		{name: "streamed over the limit", maxSize: 99, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{Transport: &pageSizeTransport{base: http.DefaultTransport, maxSize: tt.maxSize}}
+			resp, err := client.Get(server.URL + tt.query)
+			if err == nil {
+				_, err = io.ReadAll(resp.Body)
+				resp.Body.Close()
+			}
+			if got := errors.Is(err, errPageTooLarge); got != tt.wantErr {
+				t.Errorf("error = %v, want page too large = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestMaxPagesWithCrawlDelay checks that pages waiting for a robots.txt
+// Crawl-delay when max-pages is reached are still saved
+func TestMaxPagesWithCrawlDelay(t *testing.T) {
+	site := http.NewServeMux()
+	site.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nCrawl-delay: 0.3\n")
+	})
+	site.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		var links strings.Builder
+		if r.URL.Path == "/" {
+			for i := 1; i <= 5; i++ {
+				fmt.Fprintf(&links, `<a href="/page%d">Page %d</a> `, i, i)
+			}
+		}
+		fmt.Fprintf(w, `<html><head><title>Page %s</title></head><body><main><h1>Page %s</h1><p>Content of %s.</p>%s</main></body></html>`,
+			r.URL.Path, r.URL.Path, r.URL.Path, links.String())
+	})
+	server := httptest.NewServer(site)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		maxPages int
+		parallel int
+	}{
+		{name: "sequential", maxPages: 2, parallel: 1},
+		{name: "parallel", maxPages: 3, parallel: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := t.TempDir()
+			cfg := loadTestConfig(t, fmt.Sprintf("urls:\n  - %s/\noutput: %s\ndepth: 2\nparallel: %d\nmax-pages: %d\nparse-sitemaps: false\n",
+				server.URL, output, tt.parallel, tt.maxPages))
+
+			s, err := New(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Start(context.Background()); !errors.Is(err, ErrLimitReached) {
+				t.Fatalf("Start() = %v, want %v", err, ErrLimitReached)
+			}
+
+			pages, err := filepath.Glob(filepath.Join(output, "*", "*.md"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pages) != tt.maxPages {
+				t.Errorf("saved %d pages, want %d: %v", len(pages), tt.maxPages, pages)
+			}
+		})
+	}
+}
+
+// loadTestConfig loads a configuration from YAML the way the command does
+func loadTestConfig(t *testing.T, yaml string) *config.Config {
+	t.Helper()
+	configFile := filepath.Join(t.TempDir(), "bullnose.yaml")
+	if err := os.WriteFile(configFile, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
@@ -0,0 +1,100 @@
+package scraper
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// frontierFile is the name of the persisted crawl frontier in the state directory
+const frontierFile = "frontier.json"
+
+// Checkpoint persists the crawl frontier and page records so an interrupted
+// crawl can be resumed
+func (s *Scraper) Checkpoint() error {
+	if err := s.frontier.Save(); err != nil {
+		return fmt.Errorf("error saving frontier: %w", err)
+	}
+	for _, output := range s.outputs {
+		if err := output.SaveRecords(); err != nil {
+			return fmt.Errorf("error saving records: %w", err)
+		}
+	}
+	return nil
+}
+
+// startCheckpoints checkpoints the crawl periodically until the returned
+// function is called
+func (s *Scraper) startCheckpoints() func() {
+	ticker := time.NewTicker(s.config.CheckpointInterval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Checkpoint(); err != nil {
+					log.Printf("Error checkpointing crawl: %v", err)
+				} else if s.config.Debug {
+					log.Printf("Checkpointed crawl state")
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		select {
+		case <-done:
+		default:
+			close(done)
+		}
+		<-stopped
+	}
+}
+
+// resume continues an interrupted crawl from the persisted frontier. It
+// reports false when resuming is disabled or there is nothing to resume.
+func (s *Scraper) resume() (bool, error) {
+	if !s.config.Resume {
+		return false, nil
+	}
+	if !s.frontier.Exists() {
+		log.Printf("No interrupted crawl found in %s, starting a new crawl", s.config.Output)
+		return false, nil
+	}
+	if err := s.frontier.Load(); err != nil {
+		return false, fmt.Errorf("error loading crawl state: %w", err)
+	}
+
+	for _, u := range s.frontier.Completed() {
+		s.markVisited(u)
+	}
+
+	pending := s.frontier.Pending()
+	for _, entry := range pending {
+		if s.seed(entry.Seed) == nil {
+			return false, fmt.Errorf("crawl state has %s under starting URL %q, which is not configured; start a new crawl without --resume", entry.URL, entry.Seed)
+		}
+	}
+	log.Printf("Resuming crawl with %d pending URLs", len(pending))
+	for _, entry := range pending {
+		if s.stopping() {
+			break
+		}
+		if err := s.collector.Visit(entry.URL); err != nil && !s.stopping() {
+			s.frontier.MarkDone(entry.URL)
+			if err != colly.ErrAlreadyVisited && s.config.Debug {
+				log.Printf("Error visiting %s: %v", entry.URL, err)
+			}
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,223 @@
+package chunker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Unit is the measure of the chunk size and overlap
+type Unit string
+
+const (
+	// UnitChars measures chunks in characters
+	UnitChars Unit = "chars"
+	// UnitTokens measures chunks in approximate tokens of four characters
+	UnitTokens Unit = "tokens"
+)
+
+// charsPerToken is the number of characters counted as one token
+const charsPerToken = 4
+
+// headingPattern matches an ATX heading line and captures its level and text
+var headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+
+// fencePattern matches the opening or closing line of a fenced code block
+var fencePattern = regexp.MustCompile("^\\s*(```+|~~~+)")
+
+// separators are tried in order to split text that is too large, from
+// paragraphs down to single characters
+var separators = []string{"\n\n", "\n", " ", ""}
+
+// Chunk is a piece of a page small enough to be embedded on its own
+type Chunk struct {
+	URL      string   `json:"url"`
+	Title    string   `json:"title"`
+	Index    int      `json:"chunk_index"`
+	Count    int      `json:"chunk_count"`
+	Headings []string `json:"heading_path"` // the headings the chunk is under, outermost first
+	Text     string   `json:"text"`
+	Chars    int      `json:"chars"`
+	Tokens   int      `json:"tokens"` // approximate
+}
+
+// Chunker splits markdown into chunks along its headings
+type Chunker struct {
+	size    int
+	overlap int
+	unit    Unit
+}
+
+// section is the text under a heading up to the next heading
+type section struct {
+	headings []string
+	text     string
+}
+
+// ParseUnit validates a unit name
+func ParseUnit(name string) (Unit, error) {
+	switch Unit(name) {
+	case UnitChars, UnitTokens:
+		return Unit(name), nil
+	}
+	return "", fmt.Errorf("unknown chunk unit %q", name)
+}
+
+// New creates a chunker for chunks of at most size units, repeating up to
+// overlap units of a chunk at the start of the next one when a section has
+// to be split
+func New(size, overlap int, unit Unit) *Chunker {
+	return &Chunker{size: size, overlap: overlap, unit: unit}
+}
+
+// Split splits the markdown content of a page into chunks. Every heading
+// starts a new chunk, and sections larger than the chunk size are split at
+// paragraphs, then lines, then words, then characters.
+func (c *Chunker) Split(pageURL, title, markdown string) []Chunk {
+	var chunks []Chunk
+	for _, sec := range sections(markdown) {
+		for _, text := range c.split(sec.text, separators) {
+			if text = strings.TrimSpace(text); text == "" {
+				continue
+			}
+			chars := utf8.RuneCountInString(text)
+			chunks = append(chunks, Chunk{
+				URL:      pageURL,
+				Title:    title,
+				Index:    len(chunks),
+				Headings: sec.headings,
+				Text:     text,
+				Chars:    chars,
+				Tokens:   tokens(chars),
+			})
+		}
+	}
+	for i := range chunks {
+		chunks[i].Count = len(chunks)
+	}
+	return chunks
+}
+
+// sections splits markdown at its headings, ignoring lines inside fenced
+// code blocks. The headings are kept in the heading path of each section
+// rather than its text, and sections without text are dropped.
+func sections(markdown string) []section {
+	var secs []section
+	headings := []string{}
+	levels := []int{}
+	var text strings.Builder
+	body := false
+	fence := ""
+
+	flush := func() {
+		if body {
+			secs = append(secs, section{headings: headings, text: text.String()})
+		}
+		text.Reset()
+		body = false
+	}
+
+	for _, line := range strings.Split(markdown, "\n") {
+		if match := fencePattern.FindStringSubmatch(line); match != nil {
+			switch {
+			case fence == "":
+				fence = match[1]
+			case strings.HasPrefix(match[1], fence):
+				fence = ""
+			}
+		} else if match := headingPattern.FindStringSubmatch(line); match != nil && fence == "" {
+			flush()
+			level := len(match[1])
+			for len(levels) > 0 && levels[len(levels)-1] >= level {
+				levels = levels[:len(levels)-1]
+				headings = headings[:len(headings)-1]
+			}
+			// Copy the path so earlier sections keep theirs
+			headings = append(append([]string{}, headings...), match[2])
+			levels = append(levels, level)
+			continue
+		}
+
+		if strings.TrimSpace(line) != "" {
+			body = true
+		}
+		text.WriteString(line + "\n")
+	}
+	flush()
+	return secs
+}
+
+// split breaks text into pieces within the chunk size using the first
+// separator found in it, splitting oversized pieces with the next ones
+func (c *Chunker) split(text string, separators []string) []string {
+	if c.measure(utf8.RuneCountInString(text)) <= c.size {
+		return []string{text}
+	}
+
+	sep, rest := "", []string{}
+	for i, s := range separators {
+		if s == "" || strings.Contains(text, s) {
+			sep, rest = s, separators[i+1:]
+			break
+		}
+	}
+
+	var parts []string
+	for _, part := range strings.Split(text, sep) {
+		switch {
+		case strings.TrimSpace(part) == "" && sep != "":
+		case c.measure(utf8.RuneCountInString(part)) > c.size && len(rest) > 0:
+			parts = append(parts, c.split(part, rest)...)
+		default:
+			parts = append(parts, part)
+		}
+	}
+	return c.merge(parts, sep)
+}
+
+// merge joins consecutive parts into chunks within the chunk size. Each
+// chunk starts with the trailing parts of the previous one that fit in the
+// overlap.
+func (c *Chunker) merge(parts []string, sep string) []string {
+	var chunks []string
+	var current []string
+	length := 0
+	sepLength := utf8.RuneCountInString(sep)
+
+	for _, part := range parts {
+		partLength := utf8.RuneCountInString(part)
+		if len(current) > 0 && c.measure(length+sepLength+partLength) > c.size {
+			chunks = append(chunks, strings.Join(current, sep))
+			for len(current) > 0 && (c.measure(length) > c.overlap || c.measure(length+sepLength+partLength) > c.size) {
+				length -= utf8.RuneCountInString(current[0])
+				if len(current) > 1 {
+					length -= sepLength
+				}
+				current = current[1:]
+			}
+		}
+		if len(current) > 0 {
+			length += sepLength
+		}
+		current = append(current, part)
+		length += partLength
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, strings.Join(current, sep))
+	}
+	return chunks
+}
+
+// measure converts a number of characters to the chunker's unit
+func (c *Chunker) measure(chars int) int {
+	if c.unit == UnitTokens {
+		return tokens(chars)
+	}
+	return chars
+}
+
+// tokens approximates the number of tokens in a number of characters
+func tokens(chars int) int {
+	return (chars + charsPerToken - 1) / charsPerToken
+}
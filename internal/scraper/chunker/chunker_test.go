@@ -0,0 +1,109 @@
+package chunker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	type chunk struct {
+		headings []string
+		text     string
+	}
+
+	tests := []struct {
+		name     string
+		size     int
+		overlap  int
+		unit     Unit
+		markdown string
+		want     []chunk
+	}{
+		{
+			name:     "headings",
+			size:     100,
+			unit:     UnitChars,
+			markdown: "# A\n\nIntro\n\n## B\n\nText b\n\n# C\n\nText c",
+			want: []chunk{
+				{headings: []string{"A"}, text: "Intro"},
+				{headings: []string{"A", "B"}, text: "Text b"},
+				{headings: []string{"C"}, text: "Text c"},
+			},
+		},
+		{
+			name:     "heading without text",
+			size:     100,
+			unit:     UnitChars,
+			markdown: "# A\n## B ##\ntext",
+			want:     []chunk{{headings: []string{"A", "B"}, text: "text"}},
+		},
+		{
+			name:     "heading in code block",
+			size:     100,
+			unit:     UnitChars,
+			markdown: "# A\n```\n# not a heading\n```",
+			want:     []chunk{{headings: []string{"A"}, text: "```\n# not a heading\n```"}},
+		},
+		{
+			name:     "paragraphs",
+			size:     12,
+			unit:     UnitChars,
+			markdown: "aaaa bbbb\n\ncccc dddd\n\neeee",
+			want: []chunk{
+				{headings: []string{}, text: "aaaa bbbb"},
+				{headings: []string{}, text: "cccc dddd"},
+				{headings: []string{}, text: "eeee"},
+			},
+		},
+		{
+			name:     "words with overlap",
+			size:     9,
+			overlap:  4,
+			unit:     UnitChars,
+			markdown: "one two three four",
+			want: []chunk{
+				{headings: []string{}, text: "one two"},
+				{headings: []string{}, text: "two three"},
+				{headings: []string{}, text: "four"},
+			},
+		},
+		{
+			name:     "characters",
+			size:     4,
+			unit:     UnitChars,
+			markdown: "abcdefghij",
+			want: []chunk{
+				{headings: []string{}, text: "abcd"},
+				{headings: []string{}, text: "efgh"},
+				{headings: []string{}, text: "ij"},
+			},
+		},
+		{
+			name:     "tokens",
+			size:     2,
+			unit:     UnitTokens,
+			markdown: "abcd efgh ijkl",
+			want: []chunk{
+				{headings: []string{}, text: "abcd"},
+				{headings: []string{}, text: "efgh"},
+				{headings: []string{}, text: "ijkl"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := New(tt.size, tt.overlap, tt.unit).Split("https://example.com", "Title", tt.markdown)
+			got := make([]chunk, len(chunks))
+			for i, c := range chunks {
+				got[i] = chunk{headings: c.Headings, text: c.Text}
+				if c.Index != i || c.Count != len(chunks) {
+					t.Errorf("chunk %d has index %d of %d", i, c.Index, c.Count)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,7 @@
 package content
 
 import (
-	"fmt"
+	"net/url"
 	"regexp"
 	"strings"
 
@@ -13,30 +13,36 @@ type ExtractionPatterns struct {
 	TitlePattern    string
 	ContentPatterns []string
 	ExcludePatterns []string
+	Strategy        Strategy
+
+	TitleSelector    string
+	ContentSelectors []string
+	ExcludeSelectors []string
 }
 
 // Extractor handles content extraction from HTML
 type Extractor struct {
-	patterns map[string]ExtractionPatterns
-}
-
-// CodeBlock represents a parsed code block with its metadata
-type CodeBlock struct {
-	Language string
-	Content  string
-	Original string
+	patterns     map[string]ExtractionPatterns
+	linksSection bool
 }
 
-// NewExtractor creates a new content extractor
-func NewExtractor(domainPatterns map[string]ExtractionPatterns) *Extractor {
+// NewExtractor creates a new content extractor. With linksSection set, the
+// links of each page are also listed in a section at the end of its content.
+func NewExtractor(domainPatterns map[string]ExtractionPatterns, linksSection bool) *Extractor {
 	return &Extractor{
-		patterns: domainPatterns,
+		patterns:     domainPatterns,
+		linksSection: linksSection,
 	}
 }
 
 // ExtractTitle extracts the title from HTML using domain-specific patterns if available
 func (e *Extractor) ExtractTitle(domain string, selection *goquery.Selection, fallbackText string) string {
-	// Try domain-specific pattern first
+	// Try domain-specific selector and pattern first
+	if patterns, ok := e.patterns[domain]; ok && patterns.TitleSelector != "" {
+		if title := strings.TrimSpace(selection.Find(patterns.TitleSelector).First().Text()); title != "" {
+			return title
+		}
+	}
 	if patterns, ok := e.patterns[domain]; ok && patterns.TitlePattern != "" {
 		titleRegex := regexp.MustCompile(patterns.TitlePattern)
 		text, _ := selection.Html()
@@ -57,12 +63,14 @@ func (e *Extractor) ExtractTitle(domain string, selection *goquery.Selection, fa
 	return fallbackText
 }
 
-// ExtractContent extracts and formats content from HTML
-func (e *Extractor) ExtractContent(domain string, selection *goquery.Selection) string {
+// ExtractContent extracts and formats content from HTML. Links are made
+// absolute using the page URL.
+func (e *Extractor) ExtractContent(domain string, pageURL *url.URL, selection *goquery.Selection) string {
 	var content strings.Builder
 
 	// Try domain-specific patterns first
-	if patterns, ok := e.patterns[domain]; ok && len(patterns.ContentPatterns) > 0 {
+	patterns, ok := e.patterns[domain]
+	if ok && len(patterns.ContentSelectors) == 0 && len(patterns.ContentPatterns) > 0 {
 		html, _ := selection.Html()
 		for _, pattern := range patterns.ContentPatterns {
 			regex := regexp.MustCompile(pattern)
@@ -85,21 +93,79 @@ func (e *Extractor) ExtractContent(domain string, selection *goquery.Selection)
 		return e.cleanupContent(content.String())
 	}
 
-	// Find the main content area, prioritizing article content
-	mainContent := selection.Find("article, [role='main'], main, .main-content, #main-content").First()
-	if mainContent.Length() == 0 {
-		mainContent = selection.Find(".content, #content, .post, #post").First()
+	// Use the domain's content selectors, or find the main content area
+	var mainContent *goquery.Selection
+	if len(patterns.ContentSelectors) > 0 {
+		mainContent = selectContent(selection, patterns.ContentSelectors)
 	}
-	if mainContent.Length() == 0 {
-		mainContent = selection.Find("body")
+	if mainContent == nil || mainContent.Length() == 0 {
+		mainContent = e.findMainContent(domain, selection)
+	}
+	if len(patterns.ExcludeSelectors) > 0 {
+		mainContent = excludeContent(mainContent, patterns.ExcludeSelectors)
 	}
 
 	// Extract content while preserving structure
-	e.extractStructuredContent(&content, mainContent)
+	e.extractStructuredContent(&content, mainContent, newRenderer(pageURL, selection))
 
 	return e.cleanupContent(content.String())
 }
 
+// selectContent returns the elements matching the selectors in the order of
+// the selectors, leaving out elements nested inside an earlier match
+func selectContent(selection *goquery.Selection, selectors []string) *goquery.Selection {
+	selected := selection.FilterFunction(func(int, *goquery.Selection) bool { return false })
+	for _, selector := range selectors {
+		selection.Find(selector).Each(func(_ int, s *goquery.Selection) {
+			if selected.Contains(s.Get(0)) || selected.IsNodes(s.Get(0)) {
+				return
+			}
+			selected = selected.AddSelection(s)
+		})
+	}
+	return selected
+}
+
+// excludeContent returns a copy of the selection without the elements
+// matching the selectors, leaving the page itself untouched
+func excludeContent(selection *goquery.Selection, selectors []string) *goquery.Selection {
+	content := selection.Clone()
+	for _, selector := range selectors {
+		content.Find(selector).Remove()
+		content = content.Not(selector)
+	}
+	return content
+}
+
+// findMainContent locates the main content area of a page using the
+// domain's strategy, falling back to the whole body
+func (e *Extractor) findMainContent(domain string, selection *goquery.Selection) *goquery.Selection {
+	strategy := e.patterns[domain].Strategy
+	if strategy == "" {
+		strategy = StrategyAuto
+	}
+
+	var mainContent *goquery.Selection
+	switch strategy {
+	case StrategyAuto, StrategySemantic:
+		// Prioritize article content
+		mainContent = selection.Find("article, [role='main'], main, .main-content, #main-content").First()
+		if mainContent.Length() == 0 {
+			mainContent = selection.Find(".content, #content, .post, #post").First()
+		}
+		if mainContent.Length() == 0 && strategy == StrategyAuto {
+			mainContent = findReadableContent(selection)
+		}
+	case StrategyReadability:
+		mainContent = findReadableContent(selection)
+	}
+
+	if mainContent == nil || mainContent.Length() == 0 {
+		mainContent = selection.Find("body")
+	}
+	return mainContent
+}
+
 // detectCodeLanguage attempts to detect the programming language from element classes
 func (e *Extractor) detectCodeLanguage(s *goquery.Selection) string {
 	// Common class patterns for code language identification
@@ -155,125 +221,6 @@ func (e *Extractor) preserveIndentation(code string) string {
 	return strings.Join(lines, "\n")
 }
 
-// extractStructuredContent extracts content with markdown formatting
-func (e *Extractor) extractStructuredContent(content *strings.Builder, selection *goquery.Selection) {
-	// Track headings and links to avoid duplicates
-	seenHeadings := make(map[string]bool)
-	seenLinks := make(map[string]string)
-	codeBlockStack := make([]*CodeBlock, 0)
-	inCodeBlock := false
-
-	selection.Find("h1, h2, h3, h4, h5, h6, p, ul, ol, li, pre, code, blockquote, a").Each(func(_ int, s *goquery.Selection) {
-		// Skip navigation elements
-		if s.ParentsFiltered("nav, .nav, .navigation, .menu, .sidebar, aside").Length() > 0 {
-			return
-		}
-
-		switch s.Get(0).Data {
-		case "h1", "h2", "h3", "h4", "h5", "h6":
-			text := strings.TrimSpace(s.Text())
-			if text != "" && !seenHeadings[text] {
-				seenHeadings[text] = true
-				content.WriteString("\n" + strings.Repeat("#", int(s.Get(0).Data[1]-'0')) + " " + text + "\n\n")
-			}
-		case "p":
-			if !inCodeBlock {
-				text := strings.TrimSpace(s.Text())
-				if text != "" {
-					content.WriteString(text + "\n\n")
-				}
-			}
-		case "ul":
-			content.WriteString("\n")
-			s.Find("li").Each(func(_ int, li *goquery.Selection) {
-				text := strings.TrimSpace(li.Text())
-				if text != "" {
-					content.WriteString("- " + text + "\n")
-				}
-			})
-			content.WriteString("\n")
-		case "ol":
-			content.WriteString("\n")
-			listIndex := 1
-			s.Find("li").Each(func(_ int, li *goquery.Selection) {
-				text := strings.TrimSpace(li.Text())
-				if text != "" {
-					content.WriteString(fmt.Sprintf("%d. %s\n", listIndex, text))
-					listIndex++
-				}
-			})
-			content.WriteString("\n")
-		case "pre":
-			lang := e.detectCodeLanguage(s)
-			if s.Find("code").Length() > 0 {
-				// Handle pre > code structure
-				s.Find("code").Each(func(_ int, code *goquery.Selection) {
-					if codeLang := e.detectCodeLanguage(code); codeLang != "" {
-						lang = codeLang
-					}
-					text := e.preserveIndentation(code.Text())
-					if text != "" {
-						block := &CodeBlock{
-							Language: lang,
-							Content:  text,
-							Original: text,
-						}
-						codeBlockStack = append(codeBlockStack, block)
-						inCodeBlock = true
-						content.WriteString("\n```" + lang + "\n")
-						content.WriteString(text)
-						content.WriteString("\n```\n\n")
-						inCodeBlock = false
-					}
-				})
-			} else {
-				// Handle pre without code
-				text := e.preserveIndentation(s.Text())
-				if text != "" {
-					block := &CodeBlock{
-						Language: lang,
-						Content:  text,
-						Original: text,
-					}
-					codeBlockStack = append(codeBlockStack, block)
-					inCodeBlock = true
-					content.WriteString("\n```" + lang + "\n")
-					content.WriteString(text)
-					content.WriteString("\n```\n\n")
-					inCodeBlock = false
-				}
-			}
-		case "code":
-			if !inCodeBlock && s.Parent().Get(0).Data != "pre" {
-				text := strings.TrimSpace(s.Text())
-				if text != "" {
-					content.WriteString("`" + text + "`")
-				}
-			}
-		case "blockquote":
-			text := strings.TrimSpace(s.Text())
-			if text != "" {
-				content.WriteString("> " + text + "\n\n")
-			}
-		case "a":
-			if href, exists := s.Attr("href"); exists {
-				text := strings.TrimSpace(s.Text())
-				if text != "" && href != "" && !strings.HasPrefix(href, "#") {
-					seenLinks[text] = href
-				}
-			}
-		}
-	})
-
-	// Add unique links at the end
-	if len(seenLinks) > 0 {
-		content.WriteString("\n## Links\n\n")
-		for text, href := range seenLinks {
-			content.WriteString(fmt.Sprintf("[%s](%s)\n", text, href))
-		}
-	}
-}
-
 // cleanupContent performs final cleanup of the extracted content
 func (e *Extractor) cleanupContent(content string) string {
 	// Split content into lines for processing
@@ -287,15 +234,8 @@ func (e *Extractor) cleanupContent(content string) string {
 
 		// Handle code block boundaries
 		if strings.HasPrefix(trimmedLine, "```") {
-			if !inCodeBlock {
-				// Starting a code block
-				inCodeBlock = true
-				processedLines = append(processedLines, "", line) // Add empty line before code block
-			} else {
-				// Ending a code block
-				inCodeBlock = false
-				processedLines = append(processedLines, line, "") // Add empty line after code block
-			}
+			inCodeBlock = !inCodeBlock
+			processedLines = append(processedLines, strings.TrimRight(line, " \t"))
 			continue
 		}
 
@@ -305,9 +245,9 @@ func (e *Extractor) cleanupContent(content string) string {
 			continue
 		}
 
-		// Handle non-code content
+		// Handle non-code content, keeping the indentation of nested lists
 		if trimmedLine != "" {
-			processedLines = append(processedLines, trimmedLine)
+			processedLines = append(processedLines, strings.TrimRight(line, " \t"))
 		} else if len(processedLines) > 0 && processedLines[len(processedLines)-1] != "" {
 			processedLines = append(processedLines, "") // Add empty line for spacing
 		}
@@ -316,16 +256,6 @@ func (e *Extractor) cleanupContent(content string) string {
 	// Join lines and perform final cleanup
 	content = strings.Join(processedLines, "\n")
 
-	// Remove any remaining multiple consecutive empty lines
-	content = regexp.MustCompile(`\n{3,}`).ReplaceAllString(content, "\n\n")
-
-	// Ensure proper spacing around headers
-	content = regexp.MustCompile(`([^\n])(#{1,6}\s)`).ReplaceAllString(content, "$1\n\n$2")
-	content = regexp.MustCompile(`(#{1,6}[^\n]+)\n([^#\n])`).ReplaceAllString(content, "$1\n\n$2")
-
-	// Clean up list formatting
-	content = regexp.MustCompile(`\n{2,}(-|\d+\.) `).ReplaceAllString(content, "\n- ")
-
 	// Final trim
 	return strings.TrimSpace(content)
 }
@@ -0,0 +1,90 @@
+package content
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// imagePattern matches the images rendered by the converter. Their URLs
+// never contain spaces or parentheses, see linkURL.
+var imagePattern = regexp.MustCompile(`(!\[(?:\\.|[^\\\]])*\]\()([^)\s]+)`)
+
+// image renders an img element as ![alt](url "title"). The caption of an
+// enclosing figure is used as the title when the image has none.
+func (r *renderer) image(s *goquery.Selection) string {
+	src := r.imageSource(s)
+	if src == "" {
+		return ""
+	}
+
+	title := strings.TrimSpace(whitespace.ReplaceAllString(s.AttrOr("title", ""), " "))
+	if title == "" {
+		title = r.caption
+	}
+
+	md := "![" + escapeAlt(s.AttrOr("alt", "")) + "](" + src
+	if title != "" {
+		md += ` "` + strings.ReplaceAll(title, `"`, `\"`) + `"`
+	}
+	return md + ")"
+}
+
+// picture renders the image of a picture element, falling back to its
+// first source when the img element has no usable URL
+func (r *renderer) picture(s *goquery.Selection) string {
+	img := s.Find("img").First()
+	if img.Length() == 0 {
+		return ""
+	}
+	if r.imageSource(img) == "" {
+		if srcset, ok := s.Find("source[srcset]").First().Attr("srcset"); ok {
+			img = img.Clone().SetAttr("src", firstCandidate(srcset))
+		}
+	}
+	return r.image(img)
+}
+
+// imageSource returns the absolute URL of an image, taking lazy loading
+// attributes and srcset into account. Inline data URLs are left out.
+func (r *renderer) imageSource(s *goquery.Selection) string {
+	candidates := []string{s.AttrOr("src", ""), s.AttrOr("data-src", "")}
+	if srcset, ok := s.Attr("srcset"); ok {
+		candidates = append(candidates, firstCandidate(srcset))
+	}
+	for _, src := range candidates {
+		src = strings.TrimSpace(src)
+		if src == "" || strings.HasPrefix(strings.ToLower(src), "data:") {
+			continue
+		}
+		return LinkURL(r.absoluteURL(src))
+	}
+	return ""
+}
+
+// firstCandidate returns the URL of the first candidate of a srcset
+func firstCandidate(srcset string) string {
+	candidate := strings.TrimSpace(strings.Split(srcset, ",")[0])
+	if fields := strings.Fields(candidate); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// escapeAlt collapses whitespace in alt text and escapes brackets
+func escapeAlt(alt string) string {
+	alt = strings.TrimSpace(whitespace.ReplaceAllString(alt, " "))
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(alt)
+}
+
+// RewriteImages replaces the URL of every image in converted markdown with
+// the result of rewrite. Code and front matter are left unchanged.
+func RewriteImages(markdown string, rewrite func(string) string) string {
+	return replaceOutsideCode(markdown, func(text string) string {
+		return imagePattern.ReplaceAllStringFunc(text, func(match string) string {
+			parts := imagePattern.FindStringSubmatch(match)
+			return parts[1] + LinkURL(rewrite(parts[2]))
+		})
+	})
+}
@@ -0,0 +1,175 @@
+package content
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// whitespace matches runs of whitespace in text nodes
+var whitespace = regexp.MustCompile(`\s+`)
+
+// lineBreak is a markdown hard line break
+const lineBreak = "\\\n"
+
+// Link is a link found in the content of a page
+type Link struct {
+	Text string
+	URL  string
+}
+
+// renderer converts the content of a single page to markdown
+type renderer struct {
+	base    *url.URL
+	links   []Link
+	seen    map[string]bool
+	caption string // caption of the figure being rendered
+}
+
+// newRenderer creates a renderer resolving links against the page URL, or
+// against the page's base element when it has one
+func newRenderer(pageURL *url.URL, selection *goquery.Selection) *renderer {
+	base := pageURL
+	if href, ok := selection.Find("base[href]").First().Attr("href"); ok && base != nil {
+		if baseHref, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			base = baseHref
+		}
+	}
+	return &renderer{base: base, seen: make(map[string]bool)}
+}
+
+// absoluteURL resolves a link against the base URL of the page
+func (r *renderer) absoluteURL(href string) string {
+	href = strings.TrimSpace(href)
+	if r.base == nil {
+		return href
+	}
+	u, err := r.base.Parse(href)
+	if err != nil {
+		return href
+	}
+	return u.String()
+}
+
+// addLink records a link for the links section, skipping repeated URLs
+func (r *renderer) addLink(text, href string) {
+	if text == "" || href == "" || r.seen[href] {
+		return
+	}
+	r.seen[href] = true
+	r.links = append(r.links, Link{Text: text, URL: href})
+}
+
+// linkTarget returns the absolute URL of a link, or an empty string for
+// links that lead nowhere outside the page
+func (r *renderer) linkTarget(s *goquery.Selection) string {
+	href, ok := s.Attr("href")
+	href = strings.TrimSpace(href)
+	if !ok || href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	return r.absoluteURL(href)
+}
+
+// inline renders the inline content of the selected elements as markdown
+// with links, emphasis, code and line breaks kept in place
+func (r *renderer) inline(s *goquery.Selection) string {
+	var md strings.Builder
+	for _, node := range s.Nodes {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			r.inlineNode(&md, child)
+		}
+	}
+	return tidyInline(md.String())
+}
+
+// tidyInline tidies the spacing left over from collapsed whitespace
+func tidyInline(md string) string {
+	lines := strings.Split(md, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(whitespace.ReplaceAllString(line, " "))
+	}
+	text := strings.Join(lines, "\n")
+	return strings.TrimSuffix(strings.TrimSpace(text), "\\")
+}
+
+// inlineNode renders a single inline node
+func (r *renderer) inlineNode(md *strings.Builder, node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		md.WriteString(whitespace.ReplaceAllString(node.Data, " "))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	s := nodeSelection(node)
+	switch node.Data {
+	case "script", "style", "noscript", "template":
+	case "br":
+		md.WriteString(lineBreak)
+	case "a":
+		text := r.inline(s)
+		href := r.linkTarget(s)
+		if text == "" || href == "" {
+			md.WriteString(text)
+			return
+		}
+		md.WriteString("[" + text + "](" + LinkURL(href) + ")")
+		r.addLink(text, href)
+	case "strong", "b":
+		md.WriteString(wrapInline(r.inline(s), "**", node))
+	case "em", "i":
+		md.WriteString(wrapInline(r.inline(s), "_", node))
+	case "del", "s", "strike":
+		md.WriteString(wrapInline(r.inline(s), "~~", node))
+	case "code", "kbd", "samp", "tt":
+		md.WriteString(inlineCode(s.Text()))
+	case "img":
+		md.WriteString(r.image(s))
+	case "picture":
+		md.WriteString(r.picture(s))
+	default:
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			r.inlineNode(md, child)
+		}
+	}
+}
+
+// wrapInline wraps rendered text in an emphasis marker. Surrounding spaces
+// stay outside the markers since markdown does not allow them inside.
+func wrapInline(text, marker string, node *html.Node) string {
+	if text == "" {
+		return ""
+	}
+	raw := nodeSelection(node).Text()
+	prefix, suffix := "", ""
+	if strings.TrimLeft(raw, " \t\n") != raw {
+		prefix = " "
+	}
+	if strings.TrimRight(raw, " \t\n") != raw {
+		suffix = " "
+	}
+	return prefix + marker + text + marker + suffix
+}
+
+// inlineCode renders text as a code span, using a longer fence when the
+// text itself contains backticks
+func inlineCode(text string) string {
+	text = strings.TrimSpace(whitespace.ReplaceAllString(text, " "))
+	if text == "" {
+		return ""
+	}
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
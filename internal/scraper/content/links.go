@@ -0,0 +1,130 @@
+package content
+
+import (
+	"regexp"
+	"strings"
+)
+
+// linkPattern matches the targets of the links and images rendered by the
+// converter. Images are matched from their "![" so that the target of an
+// image inside a link is told apart from the target of the link.
+var linkPattern = regexp.MustCompile(`(!\[(?:\\.|[^\\\]])*)?\]\(([^)\s]+)`)
+
+// LinkURL escapes the characters that would end a markdown link target
+func LinkURL(u string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(u)
+}
+
+// fencePattern matches the fence of a fenced code block, which is indented
+// or quoted when the block is nested in a list or block quote
+var fencePattern = regexp.MustCompile("^[ \t>]*(```+|~~~+)")
+
+// RewriteLinks replaces the target of every link in converted markdown with
+// the result of rewrite. Images, code and front matter are left unchanged.
+func RewriteLinks(markdown string, rewrite func(string) string) string {
+	return replaceOutsideCode(markdown, func(text string) string {
+		return linkPattern.ReplaceAllStringFunc(text, func(match string) string {
+			parts := linkPattern.FindStringSubmatch(match)
+			if parts[1] != "" {
+				return match
+			}
+			return "](" + LinkURL(rewrite(parts[2]))
+		})
+	})
+}
+
+// replaceOutsideCode applies replace to the parts of markdown outside its
+// front matter, fenced code blocks and code spans, so code samples showing
+// markdown are kept as written
+func replaceOutsideCode(markdown string, replace func(string) string) string {
+	var out strings.Builder
+	for _, delimiter := range []string{"---\n", "+++\n"} {
+		if !strings.HasPrefix(markdown, delimiter) {
+			continue
+		}
+		if end := strings.Index(markdown[len(delimiter):], "\n"+delimiter); end >= 0 {
+			header := len(delimiter) + end + 1 + len(delimiter)
+			out.WriteString(markdown[:header])
+			markdown = markdown[header:]
+		}
+		break
+	}
+
+	var text strings.Builder
+	fence := ""
+	for _, line := range strings.SplitAfter(markdown, "\n") {
+		match := fencePattern.FindStringSubmatch(line)
+		switch {
+		case fence != "":
+			out.WriteString(line)
+			if match != nil && match[1][0] == fence[0] && len(match[1]) >= len(fence) &&
+				strings.TrimSpace(line[len(match[0]):]) == "" {
+				fence = ""
+			}
+		case match != nil:
+			out.WriteString(replaceOutsideCodeSpans(text.String(), replace))
+			text.Reset()
+			out.WriteString(line)
+			fence = match[1]
+		default:
+			text.WriteString(line)
+		}
+	}
+	out.WriteString(replaceOutsideCodeSpans(text.String(), replace))
+	return out.String()
+}
+
+// replaceOutsideCodeSpans applies replace to the text between code spans.
+// A span closes at the next run of as many backticks within its paragraph,
+// and backticks without one are literal.
+func replaceOutsideCodeSpans(text string, replace func(string) string) string {
+	var out strings.Builder
+	plain := 0
+	for i := 0; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		run := backtickRun(text, i)
+		end := closingBackticks(text, i+run, run)
+		if end < 0 {
+			i += run
+			continue
+		}
+		out.WriteString(replace(text[plain:i]))
+		out.WriteString(text[i : end+run])
+		i = end + run
+		plain = i
+	}
+	out.WriteString(replace(text[plain:]))
+	return out.String()
+}
+
+// closingBackticks returns the index of the first run of exactly run
+// backticks in text from start, stopping at the end of the paragraph, or -1
+func closingBackticks(text string, start, run int) int {
+	if paragraphEnd := strings.Index(text[start:], "\n\n"); paragraphEnd >= 0 {
+		text = text[:start+paragraphEnd]
+	}
+	for i := start; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		n := backtickRun(text, i)
+		if n == run {
+			return i
+		}
+		i += n
+	}
+	return -1
+}
+
+// backtickRun returns the number of backticks starting at index i of text
+func backtickRun(text string, i int) int {
+	n := 0
+	for i+n < len(text) && text[i+n] == '`' {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,67 @@
+package content
+
+import "testing"
+
+func TestRewriteLinks(t *testing.T) {
+	rewrite := func(target string) string { return "local.md" }
+
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "link",
+			markdown: "See [the guide](https://example.com/guide).",
+			want:     "See [the guide](local.md).",
+		},
+		{
+			name:     "image",
+			markdown: "![Diagram](https://example.com/a.png)",
+			want:     "![Diagram](https://example.com/a.png)",
+		},
+		{
+			name:     "link with code text",
+			markdown: "[`Open()`](https://example.com/open)",
+			want:     "[`Open()`](local.md)",
+		},
+		{
+			name:     "code span",
+			markdown: "Write `[text](https://example.com)` for a [link](https://example.com/a).",
+			want:     "Write `[text](https://example.com)` for a [link](local.md).",
+		},
+		{
+			name:     "longer code span",
+			markdown: "``[a`b](https://example.com)`` and [c](https://example.com/c)",
+			want:     "``[a`b](https://example.com)`` and [c](local.md)",
+		},
+		{
+			name:     "literal backtick",
+			markdown: "A ` then [c](https://example.com/c)\n\nand ` [d](https://example.com/d)",
+			want:     "A ` then [c](local.md)\n\nand ` [d](local.md)",
+		},
+		{
+			name:     "fenced code block",
+			markdown: "```markdown\n[text](https://example.com)\n```\n\n[after](https://example.com/after)",
+			want:     "```markdown\n[text](https://example.com)\n```\n\n[after](local.md)",
+		},
+		{
+			name:     "nested fenced code block",
+			markdown: "- Item\n\n  ~~~\n  [text](https://example.com)\n  ~~~\n- [next](https://example.com/next)",
+			want:     "- Item\n\n  ~~~\n  [text](https://example.com)\n  ~~~\n- [next](local.md)",
+		},
+		{
+			name:     "front matter",
+			markdown: "---\ntitle: \"[a](https://example.com)\"\n---\n\n[b](https://example.com/b)",
+			want:     "---\ntitle: \"[a](https://example.com)\"\n---\n\n[b](local.md)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RewriteLinks(tt.markdown, rewrite); got != tt.want {
+				t.Errorf("RewriteLinks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
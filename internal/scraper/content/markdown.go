@@ -0,0 +1,313 @@
+package content
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// skippedElements lists navigation and other elements that are never content
+var skippedElements = map[string]bool{
+	"nav": true, "aside": true, "script": true, "style": true,
+	"noscript": true, "template": true, "head": true,
+}
+
+// skippedClasses lists the classes of navigation elements
+var skippedClasses = map[string]bool{
+	"nav": true, "navigation": true, "menu": true, "sidebar": true,
+}
+
+// blockElements lists the elements that start a new block. Other elements
+// are rendered inline.
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"body": true, "dd": true, "details": true, "dialog": true, "div": true,
+	"dl": true, "dt": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hgroup": true, "hr": true, "html": true, "li": true, "main": true,
+	"nav": true, "ol": true, "p": true, "pre": true, "section": true,
+	"summary": true, "table": true, "ul": true,
+}
+
+// block is a rendered block of markdown
+type block struct {
+	text string
+	list bool // lists nest directly below the text of a list item
+}
+
+// converter walks the DOM of a page and renders it as markdown blocks
+type converter struct {
+	e       *Extractor
+	r       *renderer
+	blocks  []block
+	pending strings.Builder // inline content of the current paragraph
+}
+
+// extractStructuredContent converts the selected elements to markdown
+func (e *Extractor) extractStructuredContent(content *strings.Builder, selection *goquery.Selection, r *renderer) {
+	c := &converter{e: e, r: r}
+	for _, node := range selection.Nodes {
+		c.node(node)
+	}
+	content.WriteString(joinBlocks(c.finish()))
+
+	// Add unique links at the end
+	if e.linksSection && len(r.links) > 0 {
+		content.WriteString("\n\n## Links\n\n")
+		for _, link := range r.links {
+			content.WriteString(fmt.Sprintf("- [%s](%s)\n", link.Text, LinkURL(link.URL)))
+		}
+	}
+}
+
+// convertChildren renders the children of a node as separate blocks
+func (c *converter) convertChildren(node *html.Node) []block {
+	child := &converter{e: c.e, r: c.r}
+	for n := node.FirstChild; n != nil; n = n.NextSibling {
+		child.node(n)
+	}
+	return child.finish()
+}
+
+// finish flushes the pending paragraph and returns the rendered blocks
+func (c *converter) finish() []block {
+	c.flush()
+	return c.blocks
+}
+
+// flush ends the current paragraph
+func (c *converter) flush() {
+	text := tidyInline(c.pending.String())
+	c.pending.Reset()
+	if text != "" {
+		c.blocks = append(c.blocks, block{text: text})
+	}
+}
+
+// add appends a rendered block, ending the current paragraph first
+func (c *converter) add(b block) {
+	c.flush()
+	if strings.TrimSpace(b.text) != "" {
+		c.blocks = append(c.blocks, b)
+	}
+}
+
+// node renders a node and its children
+func (c *converter) node(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		c.r.inlineNode(&c.pending, node)
+		return
+	case html.ElementNode, html.DocumentNode:
+	default:
+		return
+	}
+
+	if node.Type == html.ElementNode && skipped(node) {
+		return
+	}
+	if node.Type == html.ElementNode && !blockElements[node.Data] && node.Data != "table" {
+		c.r.inlineNode(&c.pending, node)
+		return
+	}
+
+	s := nodeSelection(node)
+
+	switch node.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		if text := c.r.inline(s); text != "" {
+			c.add(block{text: strings.Repeat("#", int(node.Data[1]-'0')) + " " + text})
+		}
+	case "figure":
+		c.figure(node)
+	case "p", "dt", "summary", "figcaption":
+		c.flush()
+		text := c.r.inline(s)
+		if node.Data == "dt" && text != "" {
+			text = "**" + text + "**"
+		}
+		c.add(block{text: text})
+	case "ul", "ol":
+		c.add(block{text: c.list(node), list: true})
+	case "blockquote":
+		c.add(block{text: quote(joinBlocks(c.convertChildren(node)))})
+	case "pre":
+		c.add(block{text: c.codeBlock(s)})
+	case "table":
+		c.add(block{text: strings.TrimSpace(renderTable(s, c.r.inline))})
+	case "hr":
+		c.add(block{text: "---"})
+	default:
+		// Containers such as div, section and article
+		c.flush()
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			c.node(child)
+		}
+		c.flush()
+	}
+}
+
+// figure renders the content of a figure. The caption becomes the title of
+// the figure's images, or a paragraph of its own when there are none.
+func (c *converter) figure(node *html.Node) {
+	s := nodeSelection(node)
+	caption := s.ChildrenFiltered("figcaption").First()
+	if caption.Length() == 0 || s.Find("img").Length() == 0 {
+		c.flush()
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			c.node(child)
+		}
+		c.flush()
+		return
+	}
+
+	c.flush()
+	previous := c.r.caption
+	c.r.caption = strings.TrimSpace(whitespace.ReplaceAllString(caption.Text(), " "))
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child != caption.Get(0) {
+			c.node(child)
+		}
+	}
+	c.flush()
+	c.r.caption = previous
+}
+
+// list renders a list with nested content indented below each item
+func (c *converter) list(node *html.Node) string {
+	ordered := node.Data == "ol"
+	number := 1
+	if start, err := strconv.Atoi(nodeSelection(node).AttrOr("start", "1")); err == nil && ordered {
+		number = start
+	}
+
+	var items []string
+	width := 2 // marker width of the previous item
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		// Lists nested directly inside a list belong to the previous item
+		if child.Data == "ul" || child.Data == "ol" {
+			if nested := c.list(child); nested != "" && len(items) > 0 {
+				pad := strings.Repeat(" ", width)
+				items[len(items)-1] += "\n" + pad + indent(nested, pad)
+			}
+			continue
+		}
+		if child.Data != "li" {
+			continue
+		}
+
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		text := joinItem(c.convertChildren(child))
+		if text == "" {
+			continue
+		}
+		width = len(marker)
+		items = append(items, marker+indent(text, strings.Repeat(" ", width)))
+	}
+	return strings.Join(items, "\n")
+}
+
+// codeBlock renders a pre element as a fenced code block
+func (c *converter) codeBlock(s *goquery.Selection) string {
+	lang := c.e.detectCodeLanguage(s)
+	code := s
+	if inner := s.ChildrenFiltered("code"); inner.Length() == 1 {
+		code = inner
+		if codeLang := c.e.detectCodeLanguage(inner); codeLang != "" {
+			lang = codeLang
+		}
+	}
+
+	text := strings.Trim(c.e.preserveIndentation(code.Text()), "\n")
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + text + "\n" + fence
+}
+
+// skipped reports whether an element is never content, by its tag name or
+// one of its classes
+func skipped(node *html.Node) bool {
+	if skippedElements[node.Data] {
+		return true
+	}
+	for _, attr := range node.Attr {
+		if attr.Key != "class" {
+			continue
+		}
+		for _, class := range strings.Fields(attr.Val) {
+			if skippedClasses[class] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// joinBlocks joins rendered blocks with blank lines
+func joinBlocks(blocks []block) string {
+	texts := make([]string, len(blocks))
+	for i, b := range blocks {
+		texts[i] = b.text
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// joinItem joins the blocks of a list item, keeping nested lists directly
+// below the text they belong to
+func joinItem(blocks []block) string {
+	var md strings.Builder
+	for i, b := range blocks {
+		if i > 0 {
+			if b.list && !blocks[i-1].list {
+				md.WriteString("\n")
+			} else {
+				md.WriteString("\n\n")
+			}
+		}
+		md.WriteString(b.text)
+	}
+	return md.String()
+}
+
+// indent indents every line but the first, leaving blank lines empty
+func indent(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = prefix + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// quote prefixes every line with a blockquote marker
+func quote(text string) string {
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = ">"
+		} else {
+			lines[i] = "> " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,301 @@
+package content
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Metadata holds what a page says about itself in meta tags, OpenGraph and
+// Twitter cards and schema.org JSON-LD
+type Metadata struct {
+	Canonical   string            `json:"canonical,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Language    string            `json:"language,omitempty"`
+	Keywords    []string          `json:"keywords,omitempty"`
+	Author      string            `json:"author,omitempty"`
+	Published   string            `json:"published,omitempty"` // as declared by the page, usually ISO 8601
+	Modified    string            `json:"modified,omitempty"`
+	Image       string            `json:"image,omitempty"`
+	OpenGraph   map[string]string `json:"open_graph,omitempty"` // og:* properties without the prefix
+	Twitter     map[string]string `json:"twitter,omitempty"`    // twitter:* properties without the prefix
+	Article     *Article          `json:"article,omitempty"`
+	Breadcrumbs []Breadcrumb      `json:"breadcrumbs,omitempty"`
+	FAQ         []Question        `json:"faq,omitempty"`
+	Product     *Product          `json:"product,omitempty"`
+}
+
+// Article holds a schema.org Article or one of its subtypes
+type Article struct {
+	Type      string `json:"type"`
+	Headline  string `json:"headline,omitempty"`
+	Author    string `json:"author,omitempty"`
+	Published string `json:"published,omitempty"`
+	Modified  string `json:"modified,omitempty"`
+	Section   string `json:"section,omitempty"`
+}
+
+// Breadcrumb is an entry of a schema.org BreadcrumbList
+type Breadcrumb struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+// Question is a question of a schema.org FAQPage with its accepted answer
+type Question struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer,omitempty"`
+}
+
+// Product holds a schema.org Product and its first offer
+type Product struct {
+	Name         string `json:"name"`
+	Description  string `json:"description,omitempty"`
+	SKU          string `json:"sku,omitempty"`
+	Brand        string `json:"brand,omitempty"`
+	Price        string `json:"price,omitempty"`
+	Currency     string `json:"currency,omitempty"`
+	Availability string `json:"availability,omitempty"`
+}
+
+// articleTypes lists the schema.org types treated as articles
+var articleTypes = map[string]bool{
+	"Article": true, "NewsArticle": true, "BlogPosting": true, "TechArticle": true,
+	"ScholarlyArticle": true, "Report": true, "SocialMediaPosting": true, "LiveBlogPosting": true,
+}
+
+// ExtractMetadata extracts the metadata of a page. Meta tags win over
+// OpenGraph and Twitter properties, which win over JSON-LD. URLs are made
+// absolute using the page URL.
+func (e *Extractor) ExtractMetadata(pageURL *url.URL, selection *goquery.Selection) Metadata {
+	r := newRenderer(pageURL, selection)
+	metadata := Metadata{
+		OpenGraph: make(map[string]string),
+		Twitter:   make(map[string]string),
+	}
+
+	if href, ok := selection.Find("link[rel~='canonical'][href]").First().Attr("href"); ok {
+		metadata.Canonical = r.absoluteURL(href)
+	}
+
+	root := selection.Filter("html")
+	if root.Length() == 0 {
+		root = selection.Find("html")
+	}
+	metadata.Language = strings.TrimSpace(root.First().AttrOr("lang", ""))
+
+	// Meta tags use name, property or http-equiv for their key
+	meta := make(map[string]string)
+	selection.Find("meta[content]").Each(func(_ int, s *goquery.Selection) {
+		key := s.AttrOr("property", s.AttrOr("name", s.AttrOr("http-equiv", "")))
+		key = strings.ToLower(strings.TrimSpace(key))
+		value := collapseSpace(s.AttrOr("content", ""))
+		if key == "" || value == "" {
+			return
+		}
+		if _, ok := meta[key]; !ok {
+			meta[key] = value
+		}
+		if name, ok := strings.CutPrefix(key, "og:"); ok && metadata.OpenGraph[name] == "" {
+			metadata.OpenGraph[name] = value
+		}
+		if name, ok := strings.CutPrefix(key, "twitter:"); ok && metadata.Twitter[name] == "" {
+			metadata.Twitter[name] = value
+		}
+	})
+
+	if metadata.Language == "" {
+		metadata.Language = meta["content-language"]
+	}
+	metadata.Description = first(meta["description"], meta["og:description"], meta["twitter:description"])
+	metadata.Author = first(meta["author"], meta["article:author"], meta["twitter:creator"])
+	metadata.Published = first(meta["article:published_time"], meta["date"], meta["dc.date"])
+	metadata.Modified = first(meta["article:modified_time"], meta["og:updated_time"], meta["last-modified"])
+	if image := first(meta["og:image"], meta["twitter:image"]); image != "" {
+		metadata.Image = r.absoluteURL(image)
+	}
+	for _, keyword := range strings.Split(meta["keywords"], ",") {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			metadata.Keywords = append(metadata.Keywords, keyword)
+		}
+	}
+
+	selection.Find("script[type='application/ld+json']").Each(func(_ int, s *goquery.Selection) {
+		var data any
+		if err := json.Unmarshal([]byte(s.Text()), &data); err != nil {
+			return
+		}
+		for _, item := range jsonLDItems(data) {
+			metadata.addJSONLD(item, r)
+		}
+	})
+
+	// Fill the gaps left by the meta tags from JSON-LD
+	if metadata.Article != nil {
+		metadata.Author = first(metadata.Author, metadata.Article.Author)
+		metadata.Published = first(metadata.Published, metadata.Article.Published)
+		metadata.Modified = first(metadata.Modified, metadata.Article.Modified)
+	}
+	if metadata.Product != nil {
+		metadata.Description = first(metadata.Description, metadata.Product.Description)
+	}
+
+	return metadata
+}
+
+// addJSONLD adds a JSON-LD item of a supported type to the metadata,
+// keeping the first item of each type
+func (m *Metadata) addJSONLD(item map[string]any, r *renderer) {
+	for _, itemType := range jsonLDTypes(item) {
+		switch {
+		case articleTypes[itemType] && m.Article == nil:
+			m.Article = &Article{
+				Type:      itemType,
+				Headline:  jsonLDText(item["headline"]),
+				Author:    jsonLDName(item["author"]),
+				Published: jsonLDText(item["datePublished"]),
+				Modified:  jsonLDText(item["dateModified"]),
+				Section:   jsonLDText(item["articleSection"]),
+			}
+		case itemType == "BreadcrumbList" && m.Breadcrumbs == nil:
+			for _, element := range jsonLDList(item["itemListElement"]) {
+				crumb := Breadcrumb{Name: first(jsonLDText(element["name"]), jsonLDName(element["item"]))}
+				if target := jsonLDURL(element["item"]); target != "" {
+					crumb.URL = r.absoluteURL(target)
+				}
+				if crumb.Name != "" {
+					m.Breadcrumbs = append(m.Breadcrumbs, crumb)
+				}
+			}
+		case itemType == "FAQPage" && m.FAQ == nil:
+			for _, question := range jsonLDList(item["mainEntity"]) {
+				q := Question{Question: jsonLDText(question["name"])}
+				if answers := jsonLDList(question["acceptedAnswer"]); len(answers) > 0 {
+					q.Answer = jsonLDText(answers[0]["text"])
+				}
+				if q.Question != "" {
+					m.FAQ = append(m.FAQ, q)
+				}
+			}
+		case itemType == "Product" && m.Product == nil:
+			m.Product = &Product{
+				Name:        jsonLDText(item["name"]),
+				Description: jsonLDText(item["description"]),
+				SKU:         jsonLDText(item["sku"]),
+				Brand:       jsonLDName(item["brand"]),
+			}
+			if offers := jsonLDList(item["offers"]); len(offers) > 0 {
+				m.Product.Price = first(jsonLDText(offers[0]["price"]), jsonLDText(offers[0]["lowPrice"]))
+				m.Product.Currency = jsonLDText(offers[0]["priceCurrency"])
+				m.Product.Availability = strings.TrimPrefix(strings.TrimPrefix(
+					jsonLDText(offers[0]["availability"]), "https://schema.org/"), "http://schema.org/")
+			}
+		}
+	}
+}
+
+// jsonLDItems flattens a JSON-LD document into its items, including the
+// items of an @graph
+func jsonLDItems(data any) []map[string]any {
+	var items []map[string]any
+	switch value := data.(type) {
+	case []any:
+		for _, element := range value {
+			items = append(items, jsonLDItems(element)...)
+		}
+	case map[string]any:
+		items = append(items, value)
+		if graph, ok := value["@graph"]; ok {
+			items = append(items, jsonLDItems(graph)...)
+		}
+	}
+	return items
+}
+
+// jsonLDTypes returns the @type of an item, which may be a list
+func jsonLDTypes(item map[string]any) []string {
+	var types []string
+	switch value := item["@type"].(type) {
+	case string:
+		types = append(types, value)
+	case []any:
+		for _, t := range value {
+			if s, ok := t.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	return types
+}
+
+// jsonLDList returns a value that may be a single object or a list of objects
+func jsonLDList(value any) []map[string]any {
+	switch value := value.(type) {
+	case map[string]any:
+		return []map[string]any{value}
+	case []any:
+		var list []map[string]any
+		for _, element := range value {
+			if object, ok := element.(map[string]any); ok {
+				list = append(list, object)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// jsonLDText returns a string or number value as text
+func jsonLDText(value any) string {
+	switch value := value.(type) {
+	case string:
+		return collapseSpace(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return ""
+}
+
+// jsonLDName returns the name of a value that is either a plain name, an
+// object with a name or a list of those
+func jsonLDName(value any) string {
+	var names []string
+	switch value := value.(type) {
+	case string:
+		return collapseSpace(value)
+	case []any:
+		for _, element := range value {
+			if name := jsonLDName(element); name != "" {
+				names = append(names, name)
+			}
+		}
+	case map[string]any:
+		return jsonLDText(value["name"])
+	}
+	return strings.Join(names, ", ")
+}
+
+// jsonLDURL returns the URL of a value that is either a URL or an object
+// with an @id or url
+func jsonLDURL(value any) string {
+	switch value := value.(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case map[string]any:
+		return first(jsonLDText(value["@id"]), jsonLDText(value["url"]))
+	}
+	return ""
+}
+
+// first returns the first non-empty value
+func first(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
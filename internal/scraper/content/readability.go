@@ -0,0 +1,208 @@
+package content
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Strategy selects how the main content of a page is located
+type Strategy string
+
+const (
+	// StrategyAuto uses semantic landmarks such as article and main, then
+	// falls back to scoring
+	StrategyAuto Strategy = "auto"
+	// StrategyReadability always scores the page to find its main content
+	StrategyReadability Strategy = "readability"
+	// StrategySemantic uses semantic landmarks, then falls back to the body
+	StrategySemantic Strategy = "semantic"
+	// StrategyBody uses the whole body
+	StrategyBody Strategy = "body"
+)
+
+// Strategies lists the supported content strategies
+var Strategies = []Strategy{StrategyAuto, StrategyReadability, StrategySemantic, StrategyBody}
+
+var (
+	// unlikelyCandidates matches class names and ids of page furniture
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|consent|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|newsletter|pager|pagination|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|tweet|twitter`)
+	// maybeCandidates rescues elements that also look like content
+	maybeCandidates = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	// positiveHints and negativeHints adjust the score of a candidate by
+	// its class names and id
+	positiveHints = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeHints = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|consent|contact|cookie|footer|footnote|gdpr|masthead|media|meta|modal|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|social|tags|tool|widget`)
+)
+
+// minParagraphLength is the length below which text does not count as a paragraph
+const minParagraphLength = 25
+
+// candidate is a node collecting the scores of the paragraphs inside it
+type candidate struct {
+	node       *html.Node
+	score      float64
+	paragraphs int
+}
+
+// findReadableContent scores the blocks of a page by how much they look like
+// article text and returns the best block together with related siblings,
+// or an empty selection when nothing looks like an article
+func findReadableContent(selection *goquery.Selection) *goquery.Selection {
+	body := selection.Find("body").First()
+	if body.Length() == 0 {
+		body = selection
+	}
+
+	// Score the parents and grandparents of every paragraph
+	candidates := make(map[*html.Node]*candidate)
+	body.Find("p, pre, td, blockquote, div, section").Each(func(_ int, s *goquery.Selection) {
+		if isUnlikelyCandidate(s) {
+			return
+		}
+		// Only divs and sections holding text directly count as paragraphs
+		if tag := goquery.NodeName(s); (tag == "div" || tag == "section") && !hasOwnText(s) {
+			return
+		}
+
+		text := strings.TrimSpace(s.Text())
+		if len(text) < minParagraphLength {
+			return
+		}
+
+		// One point per paragraph, one per comma and up to three for length
+		score := 1 + float64(strings.Count(text, ",")) + float64(min(len(text)/100, 3))
+
+		parent := s.Parent()
+		for level := 0; level < 2 && parent.Length() > 0 && goquery.NodeName(parent) != "html"; level++ {
+			c := candidates[parent.Get(0)]
+			if c == nil {
+				c = &candidate{node: parent.Get(0), score: initialScore(parent)}
+				candidates[parent.Get(0)] = c
+			}
+			c.paragraphs++
+			if level == 0 {
+				c.score += score
+			} else {
+				c.score += score / 2
+			}
+			parent = parent.Parent()
+		}
+	})
+
+	// Penalize blocks that are mostly links, such as navigation and link lists
+	var top *candidate
+	for _, c := range candidates {
+		c.score *= 1 - linkDensity(nodeSelection(c.node))
+		if top == nil || c.score > top.score {
+			top = c
+		}
+	}
+	if top == nil || top.paragraphs < 2 {
+		return &goquery.Selection{}
+	}
+
+	// Keep siblings that score well or read like paragraphs of the same article
+	topSelection := nodeSelection(top.node)
+	if topSelection.Parent().Length() == 0 {
+		return topSelection
+	}
+	threshold := max(10, top.score*0.2)
+	return topSelection.Parent().Children().FilterFunction(func(_ int, s *goquery.Selection) bool {
+		node := s.Get(0)
+		if node == top.node {
+			return true
+		}
+		if c, ok := candidates[node]; ok && c.score >= threshold {
+			return true
+		}
+		if goquery.NodeName(s) == "p" {
+			text := strings.TrimSpace(s.Text())
+			density := linkDensity(s)
+			return (len(text) > 80 && density < 0.25) || (len(text) > 0 && density == 0 && strings.Contains(text, ". "))
+		}
+		return false
+	})
+}
+
+// initialScore returns the starting score of a candidate from its tag and
+// class names
+func initialScore(s *goquery.Selection) float64 {
+	var score float64
+	switch goquery.NodeName(s) {
+	case "article":
+		score = 10
+	case "div", "section":
+		score = 5
+	case "pre", "td", "blockquote":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form", "aside":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th", "header", "footer", "nav":
+		score = -5
+	}
+	return score + classWeight(s)
+}
+
+// classWeight scores the class names and id of an element
+func classWeight(s *goquery.Selection) float64 {
+	var weight float64
+	for _, attr := range []string{"class", "id"} {
+		value, ok := s.Attr(attr)
+		if !ok || value == "" {
+			continue
+		}
+		if negativeHints.MatchString(value) {
+			weight -= 25
+		}
+		if positiveHints.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// isUnlikelyCandidate reports whether an element or one of its ancestors is
+// page furniture such as navigation, cookie banners or footers
+func isUnlikelyCandidate(s *goquery.Selection) bool {
+	if s.Closest("nav, aside, footer, header, form, [role='navigation'], [role='complementary'], [role='contentinfo'], [aria-hidden='true']").Length() > 0 {
+		return true
+	}
+	for node := s; node.Length() > 0 && goquery.NodeName(node) != "body"; node = node.Parent() {
+		match := node.AttrOr("class", "") + " " + node.AttrOr("id", "")
+		if unlikelyCandidates.MatchString(match) && !maybeCandidates.MatchString(match) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOwnText reports whether an element contains text outside its child elements
+func hasOwnText(s *goquery.Selection) bool {
+	for child := s.Get(0).FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.TextNode && strings.TrimSpace(child.Data) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// linkDensity returns the share of an element's text that is link text
+func linkDensity(s *goquery.Selection) float64 {
+	textLength := len(strings.TrimSpace(s.Text()))
+	if textLength == 0 {
+		return 0
+	}
+	linkLength := 0
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linkLength += len(strings.TrimSpace(a.Text()))
+	})
+	return float64(linkLength) / float64(textLength)
+}
+
+// nodeSelection wraps a node of the page in a selection
+func nodeSelection(node *html.Node) *goquery.Selection {
+	return goquery.NewDocumentFromNode(node).Selection
+}
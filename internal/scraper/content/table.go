@@ -0,0 +1,163 @@
+package content
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// complexTableContent matches cell content that markdown tables cannot hold
+const complexTableContent = "table, ul, ol, pre, blockquote, h1, h2, h3, h4, h5, h6"
+
+// maxSpan caps colspan and rowspan values so broken markup cannot blow up a table
+const maxSpan = 100
+
+// tableCell is a cell of the table grid
+type tableCell struct {
+	text   string
+	header bool
+}
+
+// renderTable converts a table to a GitHub flavored markdown table. Cells
+// spanning several columns or rows are kept in their first position and
+// padded with empty cells. Tables that cannot be represented in markdown,
+// such as nested tables or cells holding lists or code blocks, are kept as
+// HTML. cellText renders the content of a cell on a single line.
+func renderTable(table *goquery.Selection, cellText func(*goquery.Selection) string) string {
+	if table.Find(complexTableContent).Length() > 0 {
+		return renderTableHTML(table)
+	}
+
+	rows, headerRows := tableGrid(table, cellText)
+	if len(rows) == 0 {
+		return ""
+	}
+	if headerRows > 1 {
+		return renderTableHTML(table)
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+
+	// A header row is taken from thead or a first row of th cells;
+	// otherwise the table gets an empty header
+	var header []tableCell
+	if headerRows == 1 || isHeaderRow(rows[0]) {
+		header, rows = rows[0], rows[1:]
+	}
+
+	var md strings.Builder
+	if caption := strings.TrimSpace(table.ChildrenFiltered("caption").Text()); caption != "" {
+		md.WriteString(collapseSpace(caption) + "\n\n")
+	}
+	writeTableRow(&md, header, columns)
+	md.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+	for _, row := range rows {
+		writeTableRow(&md, row, columns)
+	}
+	return md.String()
+}
+
+// tableGrid lays out the rows of a table as a grid, resolving colspan and
+// rowspan. It also returns the number of rows in the table head.
+func tableGrid(table *goquery.Selection, cellText func(*goquery.Selection) string) ([][]tableCell, int) {
+	var rows [][]tableCell
+	headerRows := 0
+	spans := map[int]int{} // column -> remaining rows covered by a rowspan
+
+	table.Find("tr").Each(func(_ int, tr *goquery.Selection) {
+		if tr.ParentsFiltered("thead").Length() > 0 {
+			headerRows++
+		}
+
+		var row []tableCell
+		column := 0
+		fillSpans := func() {
+			for spans[column] > 0 {
+				spans[column]--
+				row = append(row, tableCell{})
+				column++
+			}
+		}
+
+		tr.ChildrenFiltered("th, td").Each(func(_ int, cell *goquery.Selection) {
+			fillSpans()
+			colspan := spanAttr(cell, "colspan")
+			rowspan := spanAttr(cell, "rowspan")
+			for i := 0; i < colspan; i++ {
+				c := tableCell{header: goquery.NodeName(cell) == "th"}
+				if i == 0 {
+					c.text = cellText(cell)
+				}
+				row = append(row, c)
+				if rowspan > 1 {
+					spans[column] = rowspan - 1
+				}
+				column++
+			}
+		})
+		fillSpans()
+		rows = append(rows, row)
+	})
+
+	return rows, headerRows
+}
+
+// spanAttr returns the colspan or rowspan of a cell
+func spanAttr(cell *goquery.Selection, name string) int {
+	span, err := strconv.Atoi(strings.TrimSpace(cell.AttrOr(name, "1")))
+	if err != nil || span < 1 {
+		return 1
+	}
+	return min(span, maxSpan)
+}
+
+// isHeaderRow reports whether all cells of a row are th cells
+func isHeaderRow(row []tableCell) bool {
+	if len(row) == 0 {
+		return false
+	}
+	for _, cell := range row {
+		if !cell.header {
+			return false
+		}
+	}
+	return true
+}
+
+// writeTableRow writes a row padded to the given number of columns
+func writeTableRow(md *strings.Builder, row []tableCell, columns int) {
+	md.WriteString("|")
+	for i := 0; i < columns; i++ {
+		text := ""
+		if i < len(row) {
+			text = escapeTableCell(row[i].text)
+		}
+		md.WriteString(" " + text + " |")
+	}
+	md.WriteString("\n")
+}
+
+// escapeTableCell escapes pipes and line breaks that would end a cell
+func escapeTableCell(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	text = strings.ReplaceAll(text, lineBreak, "<br>")
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
+
+// renderTableHTML keeps a table as embedded HTML
+func renderTableHTML(table *goquery.Selection) string {
+	html, err := goquery.OuterHtml(table)
+	if err != nil {
+		return ""
+	}
+	return html + "\n"
+}
+
+// collapseSpace replaces runs of whitespace with a single space
+func collapseSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
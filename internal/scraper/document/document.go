@@ -0,0 +1,167 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ncecere/bullnose/internal/scraper/content"
+)
+
+// Format is the kind of header written at the top of each document
+type Format string
+
+const (
+	// FormatNone writes the title followed by a Metadata section
+	FormatNone Format = "none"
+	// FormatYAML writes YAML front matter between --- lines
+	FormatYAML Format = "yaml"
+	// FormatTOML writes TOML front matter between +++ lines
+	FormatTOML Format = "toml"
+)
+
+// Formats lists all supported header formats
+var Formats = []Format{FormatNone, FormatYAML, FormatTOML}
+
+// ReservedFields are the front matter keys written for every page, which
+// custom fields cannot replace
+var ReservedFields = []string{
+	"url", "canonical", "title", "description", "language", "author", "keywords",
+	"published", "modified", "image", "scraped_at", "content_hash", "depth",
+}
+
+// Page describes a saved page in the header of its document. The metadata
+// of the page is flattened into the common front matter fields.
+type Page struct {
+	URL         string            `yaml:"url" toml:"url"`
+	Canonical   string            `yaml:"canonical,omitempty" toml:"canonical,omitempty"`
+	Title       string            `yaml:"title" toml:"title"`
+	Description string            `yaml:"description,omitempty" toml:"description,omitempty"`
+	Language    string            `yaml:"language,omitempty" toml:"language,omitempty"`
+	Author      string            `yaml:"author,omitempty" toml:"author,omitempty"`
+	Keywords    []string          `yaml:"keywords,omitempty" toml:"keywords,omitempty"`
+	Published   string            `yaml:"published,omitempty" toml:"published,omitempty"`
+	Modified    string            `yaml:"modified,omitempty" toml:"modified,omitempty"`
+	Image       string            `yaml:"image,omitempty" toml:"image,omitempty"`
+	ScrapedAt   time.Time         `yaml:"scraped_at" toml:"scraped_at"`
+	ContentHash string            `yaml:"content_hash" toml:"content_hash"`
+	Depth       int               `yaml:"depth" toml:"depth"`
+	Fields      map[string]string `yaml:"-" toml:"-"` // custom fields from the configuration
+	Metadata    content.Metadata  `yaml:"-" toml:"-"` // everything extracted from the page, for templates
+}
+
+// Renderer writes documents with the configured header
+type Renderer struct {
+	format   Format
+	template *template.Template
+	fields   map[string]string
+}
+
+// ParseFormat validates a header format name
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown front matter format %q", name)
+}
+
+// NewRenderer creates a renderer for the given header format. A non-empty
+// header template replaces the format and is executed with the Page of
+// each document. Custom fields are added to every header.
+func NewRenderer(format Format, headerTemplate string, fields map[string]string) (*Renderer, error) {
+	for _, key := range ReservedFields {
+		if _, ok := fields[key]; ok {
+			return nil, fmt.Errorf("front matter field %s is reserved", key)
+		}
+	}
+
+	r := &Renderer{format: format, fields: fields}
+	if headerTemplate != "" {
+		tmpl, err := template.New("header").Option("missingkey=zero").Parse(headerTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid header template: %w", err)
+		}
+		r.template = tmpl
+	}
+	return r, nil
+}
+
+// Render returns the document of a page: its header followed by the content
+func (r *Renderer) Render(page Page, content string) (string, error) {
+	page.ScrapedAt = page.ScrapedAt.UTC().Truncate(time.Second)
+	page.Fields = r.fields
+
+	var doc strings.Builder
+	switch {
+	case r.template != nil:
+		if err := r.template.Execute(&doc, page); err != nil {
+			return "", fmt.Errorf("failed to execute header template: %w", err)
+		}
+	case r.format == FormatYAML:
+		header, err := r.frontMatter(page, yaml.Marshal)
+		if err != nil {
+			return "", err
+		}
+		doc.WriteString("---\n" + header + "---\n\n")
+	case r.format == FormatTOML:
+		header, err := r.frontMatter(page, toml.Marshal)
+		if err != nil {
+			return "", err
+		}
+		doc.WriteString("+++\n" + header + "+++\n\n")
+	default:
+		doc.WriteString(fmt.Sprintf("# %s\n", page.Title))
+		doc.WriteString("\n## Metadata\n")
+		doc.WriteString(fmt.Sprintf("- URL: %s\n", page.URL))
+		if page.Description != "" {
+			doc.WriteString(fmt.Sprintf("- Description: %s\n", page.Description))
+		}
+		if page.Author != "" {
+			doc.WriteString(fmt.Sprintf("- Author: %s\n", page.Author))
+		}
+		if page.Published != "" {
+			doc.WriteString(fmt.Sprintf("- Published: %s\n", page.Published))
+		}
+		if page.Modified != "" {
+			doc.WriteString(fmt.Sprintf("- Modified: %s\n", page.Modified))
+		}
+		doc.WriteString(fmt.Sprintf("- Scraped: %s\n", page.ScrapedAt.Format(time.RFC3339)))
+		doc.WriteString("\n## Content\n")
+	}
+
+	doc.WriteString(content)
+	return doc.String(), nil
+}
+
+// frontMatter encodes the page followed by the custom fields in key order
+func (r *Renderer) frontMatter(page Page, marshal func(any) ([]byte, error)) (string, error) {
+	var header bytes.Buffer
+	data, err := marshal(page)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode front matter: %w", err)
+	}
+	header.Write(data)
+
+	keys := make([]string, 0, len(r.fields))
+	for key := range r.fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		data, err := marshal(map[string]string{key: r.fields[key]})
+		if err != nil {
+			return "", fmt.Errorf("failed to encode front matter field %s: %w", key, err)
+		}
+		header.Write(data)
+	}
+
+	return header.String(), nil
+}
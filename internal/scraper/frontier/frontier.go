@@ -0,0 +1,153 @@
+package frontier
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Entry is a URL discovered during a crawl
+type Entry struct {
+	URL    string `json:"url"`
+	Depth  int    `json:"depth"`
+	Parent string `json:"parent,omitempty"`
+	Seed   string `json:"seed"` // starting URL the entry was discovered from
+	Done   bool   `json:"done,omitempty"`
+}
+
+// Frontier tracks every URL discovered during a crawl, its depth and
+// whether it has been processed, and can be persisted to resume a crawl
+type Frontier struct {
+	path    string
+	entries []*Entry
+	index   map[string]*Entry
+	mutex   sync.Mutex
+}
+
+// New creates an empty frontier persisted at path
+func New(path string) *Frontier {
+	return &Frontier{
+		path:  path,
+		index: make(map[string]*Entry),
+	}
+}
+
+// Exists reports whether a persisted frontier is present
+func (f *Frontier) Exists() bool {
+	_, err := os.Stat(f.path)
+	return err == nil
+}
+
+// Load reads the persisted frontier, replacing the current state
+func (f *Frontier) Load() error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to read frontier: %w", err)
+	}
+
+	var entries []*Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse frontier: %w", err)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.entries = entries
+	f.index = make(map[string]*Entry, len(entries))
+	for _, entry := range entries {
+		f.index[entry.URL] = entry
+	}
+	return nil
+}
+
+// Save persists the frontier, preserving discovery order
+func (f *Frontier) Save() error {
+	f.mutex.Lock()
+	data, err := json.Marshal(f.entries)
+	f.mutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode frontier: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write frontier: %w", err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("failed to write frontier: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the persisted frontier once a crawl has completed
+func (f *Frontier) Remove() error {
+	if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove frontier: %w", err)
+	}
+	return nil
+}
+
+// Add records a newly discovered URL along with the seed it was discovered
+// from and reports whether it was new
+func (f *Frontier) Add(url string, depth int, parent, seed string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if _, ok := f.index[url]; ok {
+		return false
+	}
+	entry := &Entry{URL: url, Depth: depth, Parent: parent, Seed: seed}
+	f.entries = append(f.entries, entry)
+	f.index[url] = entry
+	return true
+}
+
+// Get returns a copy of the entry for a URL
+func (f *Frontier) Get(url string) (Entry, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	entry, ok := f.index[url]
+	if !ok {
+		return Entry{}, false
+	}
+	return *entry, true
+}
+
+// MarkDone records that a URL has been fully processed
+func (f *Frontier) MarkDone(url string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if entry, ok := f.index[url]; ok {
+		entry.Done = true
+	}
+}
+
+// Pending returns the entries not processed yet, in discovery order
+func (f *Frontier) Pending() []Entry {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var pending []Entry
+	for _, entry := range f.entries {
+		if !entry.Done {
+			pending = append(pending, *entry)
+		}
+	}
+	return pending
+}
+
+// Completed returns the URLs already processed
+func (f *Frontier) Completed() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var done []string
+	for _, entry := range f.entries {
+		if entry.Done {
+			done = append(done, entry.URL)
+		}
+	}
+	return done
+}
@@ -0,0 +1,112 @@
+package frontier
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFrontier(t *testing.T) {
+	const seed = "https://example.com/"
+
+	tests := []struct {
+		name      string
+		add       []string
+		done      []string
+		pending   []string
+		completed []string
+	}{
+		{name: "empty"},
+		{
+			name:    "discovery order",
+			add:     []string{"https://example.com/", "https://example.com/b", "https://example.com/a"},
+			pending: []string{"https://example.com/", "https://example.com/b", "https://example.com/a"},
+		},
+		{
+			name:    "added once",
+			add:     []string{"https://example.com/a", "https://example.com/a"},
+			pending: []string{"https://example.com/a"},
+		},
+		{
+			name:      "done",
+			add:       []string{"https://example.com/", "https://example.com/a", "https://example.com/b"},
+			done:      []string{"https://example.com/a", "https://example.com/unknown"},
+			pending:   []string{"https://example.com/", "https://example.com/b"},
+			completed: []string{"https://example.com/a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "frontier.json")
+			f := New(path)
+			for _, u := range tt.add {
+				f.Add(u, 1, "", seed)
+			}
+			for _, u := range tt.done {
+				f.MarkDone(u)
+			}
+			if err := f.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			// A loaded frontier matches the one that was saved
+			loaded := New(path)
+			if err := loaded.Load(); err != nil {
+				t.Fatal(err)
+			}
+			for _, frontier := range []*Frontier{f, loaded} {
+				var pending []string
+				for _, entry := range frontier.Pending() {
+					pending = append(pending, entry.URL)
+				}
+				if !reflect.DeepEqual(pending, tt.pending) {
+					t.Errorf("Pending() = %v, want %v", pending, tt.pending)
+				}
+				if completed := frontier.Completed(); !reflect.DeepEqual(completed, tt.completed) {
+					t.Errorf("Completed() = %v, want %v", completed, tt.completed)
+				}
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	f := New(filepath.Join(t.TempDir(), "frontier.json"))
+	if !f.Add("https://example.com/a", 2, "https://example.com/", "https://example.com/") {
+		t.Error("Add() = false for a new URL")
+	}
+	if f.Add("https://example.com/a", 1, "https://example.com/b", "https://example.com/b") {
+		t.Error("Add() = true for a known URL")
+	}
+
+	want := Entry{URL: "https://example.com/a", Depth: 2, Parent: "https://example.com/", Seed: "https://example.com/"}
+	if got, ok := f.Get("https://example.com/a"); !ok || got != want {
+		t.Errorf("Get() = %+v, %v, want %+v", got, ok, want)
+	}
+	if _, ok := f.Get("https://example.com/b"); ok {
+		t.Error("Get() found a URL that was never added")
+	}
+}
+
+func TestRemove(t *testing.T) {
+	f := New(filepath.Join(t.TempDir(), "state", "frontier.json"))
+	if f.Exists() {
+		t.Fatal("Exists() = true before saving")
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Exists() {
+		t.Fatal("Exists() = false after saving")
+	}
+	if err := f.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if f.Exists() {
+		t.Error("Exists() = true after removing")
+	}
+	if err := f.Remove(); err != nil {
+		t.Errorf("Remove() of a missing frontier = %v", err)
+	}
+}
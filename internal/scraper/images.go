@@ -0,0 +1,107 @@
+package scraper
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/ncecere/bullnose/internal/scraper/content"
+	"github.com/ncecere/bullnose/internal/scraper/storage"
+)
+
+// localImages downloads the images of a page saved by output into the
+// assets directory next to its markdown and links them to the local copies.
+// Images that cannot be downloaded keep their original URL.
+func (s *Scraper) localImages(markdown, outputPath string, output *storage.Storage) string {
+	return content.RewriteImages(markdown, func(src string) string {
+		assetPath, err := s.saveImage(src, outputPath, output)
+		if err != nil {
+			if s.config.Debug {
+				log.Printf("Not downloading image %s: %v", src, err)
+			}
+			return src
+		}
+		return output.RelativeLink(outputPath, assetPath)
+	})
+}
+
+// saveImage stores an image in the assets directory of a page, reusing an
+// earlier download of the same URL
+func (s *Scraper) saveImage(src, outputPath string, output *storage.Storage) (string, error) {
+	var data []byte
+	var ext string
+	if saved, ok := s.saved.Load(src); ok {
+		if filepath.Dir(saved.(string)) == output.AssetDir(outputPath) {
+			return saved.(string), nil
+		}
+		data, _ = os.ReadFile(saved.(string))
+		ext = filepath.Ext(saved.(string))
+	}
+
+	if data == nil {
+		var err error
+		if data, ext, err = s.images.Fetch(s.ctx, src); err != nil {
+			return "", err
+		}
+		s.stats.IncrementImages()
+		s.addBytes(len(data))
+	}
+
+	assetPath, err := output.SaveAsset(outputPath, data, ext)
+	if err != nil {
+		return "", err
+	}
+	s.saved.Store(src, assetPath)
+	return assetPath, nil
+}
+
+// prepareImageRequest applies the rules of the crawl to an image download:
+// the allowed domains, ignore patterns and robots.txt, the rate limits of
+// the image's host and its configured headers and cookies
+func (s *Scraper) prepareImageRequest(req *http.Request) error {
+	if !s.allowedURL(req.URL) {
+		return fmt.Errorf("outside the crawled domains or ignored")
+	}
+
+	if s.robots != nil && !s.robots.Allowed(s.ctx, req.URL) {
+		return fmt.Errorf("blocked by robots.txt")
+	}
+	if err := s.pace(req.URL); err != nil {
+		return err
+	}
+
+	req.Header.Set("User-Agent", s.config.UserAgent)
+	s.setDomainHeaders(req.URL.Host, req.Header)
+	return nil
+}
+
+// allowedURL reports whether the collector's domain restriction and ignore
+// patterns allow a URL
+func (s *Scraper) allowedURL(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	if len(s.collector.AllowedDomains) > 0 {
+		allowed := false
+		for _, domain := range s.collector.AllowedDomains {
+			if domain == u.Host || domain == u.Hostname() {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	for _, filter := range s.collector.DisallowedURLFilters {
+		if filter.MatchString(u.String()) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+
+	"github.com/ncecere/bullnose/internal/config"
+	"github.com/ncecere/bullnose/internal/scraper/ratelimit"
+)
+
+// defaultRandomDelay is the random delay added after each request when
+// crawling in parallel
+const defaultRandomDelay = 1 * time.Second
+
+// domainLimit is the resolved rate limit of a domain-config entry
+type domainLimit struct {
+	pattern string
+	rule    *colly.LimitRule
+	rps     float64
+}
+
+// String describes the limit for the statistics summary
+func (l domainLimit) String() string {
+	limits := fmt.Sprintf("parallelism %d, delay %s, random delay %s", l.rule.Parallelism, l.rule.Delay, l.rule.RandomDelay)
+	if l.rps > 0 {
+		limits += fmt.Sprintf(", %g req/s", l.rps)
+	}
+	return limits
+}
+
+// domainLimits builds a limit rule for every domain-config entry that sets
+// rate limits. Settings left unset fall back to the global limits. Rules are
+// ordered from most to least specific because colly applies the first
+// matching rule.
+func domainLimits(cfg *config.Config) []domainLimit {
+	var limits []domainLimit
+	for pattern, domainCfg := range cfg.DomainConfig {
+		if domainCfg == nil || !domainCfg.HasLimits() {
+			continue
+		}
+
+		rule := &colly.LimitRule{
+			DomainGlob:  hostGlob(pattern),
+			Parallelism: cfg.Parallel,
+			Delay:       domainCfg.Delay,
+			RandomDelay: globalRandomDelay(cfg),
+		}
+		if domainCfg.Parallelism > 0 {
+			rule.Parallelism = domainCfg.Parallelism
+		}
+		if domainCfg.RandomDelay != nil {
+			rule.RandomDelay = *domainCfg.RandomDelay
+		}
+
+		limits = append(limits, domainLimit{
+			pattern: pattern,
+			rule:    rule,
+			rps:     domainCfg.RequestsPerSecond,
+		})
+	}
+
+	sort.Slice(limits, func(i, j int) bool {
+		a, b := limits[i].pattern, limits[j].pattern
+		if isGlob(a) != isGlob(b) {
+			return !isGlob(a)
+		}
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a < b
+	})
+	return limits
+}
+
+// domainLimit returns the domain limit that applies to a host, which is the
+// same rule colly picks for the request
+func (s *Scraper) domainLimit(host string) (domainLimit, bool) {
+	for _, limit := range s.limits {
+		if limit.rule.Match(host) {
+			return limit, true
+		}
+	}
+	return domainLimit{}, false
+}
+
+// pace waits for the robots.txt Crawl-delay and the requests-per-second cap
+// of the domain limit that apply to a URL, counting the request against the
+// domain limit
+func (s *Scraper) pace(u *url.URL) error {
+	if s.robots != nil {
+		if err := s.pacer.Wait(s.ctx, "robots:"+u.Host, s.robots.CrawlDelay(s.ctx, u)); err != nil {
+			return err
+		}
+	}
+	if limit, ok := s.domainLimit(u.Host); ok {
+		if err := s.pacer.Wait(s.ctx, "domain:"+limit.pattern, ratelimit.Interval(limit.rps)); err != nil {
+			return err
+		}
+		s.stats.IncrementDomain(limit.pattern)
+	}
+	return nil
+}
+
+// globalRandomDelay returns the random delay of the global limit rule
+func globalRandomDelay(cfg *config.Config) time.Duration {
+	if cfg.Parallel > 1 {
+		return defaultRandomDelay
+	}
+	return 0
+}
+
+// hostGlob converts a domain pattern to a glob that also matches the host
+// with a port, as colly matches limit rules against the URL host
+func hostGlob(pattern string) string {
+	pattern = strings.ToLower(pattern)
+	if strings.Contains(pattern, ":") {
+		return pattern
+	}
+	return "{" + pattern + "," + pattern + ":*}"
+}
+
+// isGlob reports whether a domain pattern contains wildcards
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
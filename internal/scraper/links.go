@@ -0,0 +1,134 @@
+package scraper
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/ncecere/bullnose/internal/scraper/content"
+	"github.com/ncecere/bullnose/internal/scraper/storage"
+)
+
+// rewriteLinks points the links between saved pages at their local files so
+// the output can be browsed offline. Links to pages outside the crawl stay
+// absolute, and links to pages within it that were never captured are
+// reported in the output directory of the linking page. Pages of seeds with
+// different outputs link to each other across the directories.
+func (s *Scraper) rewriteLinks() error {
+	// Pages are found by any spelling of their requested and final URL, as
+	// long as their file is still there
+	outputPaths := make(map[string]string)
+	for _, output := range s.outputs {
+		for _, page := range output.Pages() {
+			if _, err := os.Stat(page.OutputPath); err != nil {
+				continue
+			}
+			for _, pageURL := range []string{page.URL, page.FinalURL} {
+				if pageURL != "" {
+					outputPaths[s.urls.Key(pageURL)] = page.OutputPath
+				}
+			}
+		}
+	}
+
+	// Copies of saved pages lead to the page they duplicate
+	for _, output := range s.outputs {
+		for duplicate, original := range output.Duplicates() {
+			if outputPath, ok := outputPaths[s.urls.Key(original)]; ok {
+				outputPaths[s.urls.Key(duplicate)] = outputPath
+			}
+		}
+	}
+
+	rewritten, missingPages := 0, make(map[string]bool)
+	for _, output := range s.outputs {
+		local, missing, err := s.rewriteOutputLinks(output, outputPaths)
+		if err != nil {
+			return err
+		}
+		rewritten += local
+		for target := range missing {
+			missingPages[target] = true
+		}
+		if err := output.WriteMissingLinks(missing); err != nil {
+			return err
+		}
+	}
+
+	s.stats.AddLinks(rewritten, len(missingPages))
+	if len(missingPages) > 0 {
+		log.Printf("Found links to %d pages that were not captured, see missing-links.json", len(missingPages))
+	}
+	return nil
+}
+
+// rewriteOutputLinks rewrites the links of the pages saved in one output
+// directory and returns the number of links made local along with the links
+// to pages that were never captured. Links made local by a previous run are
+// resolved again from the URLs they replaced, so pages skipped in this run
+// follow pages that moved or were removed.
+func (s *Scraper) rewriteOutputLinks(output *storage.Storage, outputPaths map[string]string) (int, map[string][]string, error) {
+	missing := make(map[string][]string)
+	rewritten := 0
+	for _, page := range output.Pages() {
+		data, err := os.ReadFile(page.OutputPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to read %s: %w", page.OutputPath, err)
+		}
+
+		linked := make(map[string]bool)
+		local := make(map[string]string)
+		markdown := content.RewriteLinks(string(data), func(target string) string {
+			if previous, ok := page.LocalLinks[target]; ok {
+				target = previous
+			}
+			u, err := url.Parse(target)
+			if err != nil {
+				return target
+			}
+			if !u.IsAbs() {
+				s.checkLocalLink(page, u)
+				return target
+			}
+			if outputPath, ok := outputPaths[s.urls.Key(target)]; ok {
+				rewritten++
+				link := output.RelativeLink(page.OutputPath, outputPath)
+				if u.Fragment != "" {
+					link += "#" + u.EscapedFragment()
+				}
+				local[content.LinkURL(link)] = target
+				return link
+			}
+			if normalized, err := s.urls.Normalize(target); err == nil && s.inScope(target) && !linked[normalized] {
+				linked[normalized] = true
+				missing[normalized] = append(missing[normalized], page.URL)
+			}
+			return target
+		})
+		output.SetLocalLinks(page.URL, local)
+
+		if markdown != string(data) {
+			if err := output.UpdateContent(page.OutputPath, markdown); err != nil {
+				return 0, nil, err
+			}
+		}
+	}
+	return rewritten, missing, nil
+}
+
+// checkLocalLink reports a relative link whose target file no longer exists
+// and whose URL is not known, such as one written by an older version
+func (s *Scraper) checkLocalLink(page storage.Record, link *url.URL) {
+	if link.Path == "" {
+		return
+	}
+	target := filepath.Join(filepath.Dir(page.OutputPath), filepath.FromSlash(link.Path))
+	if _, err := os.Stat(target); os.IsNotExist(err) {
+		log.Printf("Broken link in %s: %s no longer exists", page.OutputPath, link.Path)
+	}
+}
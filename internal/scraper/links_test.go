@@ -0,0 +1,89 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestRewriteLinksAcrossRuns checks that links made local by a previous run
+// follow the pages they point at, including from pages that were not
+// modified and kept their stored markdown
+func TestRewriteLinksAcrossRuns(t *testing.T) {
+	var mutex sync.Mutex
+	titles := map[string]string{"/": "Home", "/guide": "Guide", "/faq": "FAQ"}
+	site := http.NewServeMux()
+	site.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		title, ok := titles[r.URL.Path]
+		mutex.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		etag := `"` + title + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		fmt.Fprintf(w, `<html><head><title>%s</title></head><body><main><h1>%s</h1><p>Content of %s.</p><a href="/guide">Guide</a> <a href="/faq">FAQ</a></main></body></html>`,
+			title, title, r.URL.Path)
+	})
+	server := httptest.NewServer(site)
+	defer server.Close()
+
+	output := t.TempDir()
+	crawl := func() {
+		cfg := loadTestConfig(t, fmt.Sprintf("urls:\n  - %s/\noutput: %s\ndepth: 2\nrescrape-after: 0s\nrewrite-links: true\nparse-sitemaps: false\nrespect-robots: false\n",
+			server.URL, output))
+		s, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	host := strings.TrimPrefix(server.URL, "http://")
+	page := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(output, host, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	crawl()
+	if home := page("home.md"); !strings.Contains(home, "[Guide](guide.md)") {
+		t.Fatalf("home.md does not link to guide.md:\n%s", home)
+	}
+
+	// Only the guide changes, so home.md is kept as it was
+	mutex.Lock()
+	titles["/guide"] = "User Guide"
+	mutex.Unlock()
+	crawl()
+
+	tests := []struct {
+		name string
+		link string
+	}{
+		{name: "moved page", link: "[Guide](user-guide.md)"},
+		{name: "unchanged page", link: "[FAQ](faq.md)"},
+	}
+	home := page("home.md")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(home, tt.link) {
+				t.Errorf("home.md does not contain %s:\n%s", tt.link, home)
+			}
+		})
+	}
+}
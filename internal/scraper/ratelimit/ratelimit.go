@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pacer spaces out requests that share a key, such as a host or a domain
+// pattern, so that they start at least a given interval apart
+type Pacer struct {
+	nextSlot map[string]time.Time
+	mutex    sync.Mutex
+}
+
+// NewPacer creates a new request pacer
+func NewPacer() *Pacer {
+	return &Pacer{
+		nextSlot: make(map[string]time.Time),
+	}
+}
+
+// Wait blocks until the next request for key may start or ctx is cancelled.
+// Slots are reserved under lock so parallel requests stay spaced out.
+func (p *Pacer) Wait(ctx context.Context, key string, interval time.Duration) error {
+	if interval <= 0 {
+		return nil
+	}
+
+	p.mutex.Lock()
+	now := time.Now()
+	slot := p.nextSlot[key]
+	if slot.Before(now) {
+		slot = now
+	}
+	p.nextSlot[key] = slot.Add(interval)
+	p.mutex.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Interval returns the minimum spacing between requests for a
+// requests-per-second cap, or zero when the cap is disabled
+func Interval(requestsPerSecond float64) time.Duration {
+	if requestsPerSecond <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / requestsPerSecond)
+}
@@ -0,0 +1,78 @@
+package scraper
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gocolly/colly/v2"
+
+	"github.com/ncecere/bullnose/internal/scraper/retry"
+)
+
+// retryRequest schedules another attempt of a request that failed with a
+// transient error, waiting for the backoff of the domain's retry policy
+// first. It reports whether the failure was handled, which is also the case
+// when the crawl stops during the backoff so the URL stays pending.
+func (s *Scraper) retryRequest(r *colly.Response, err error) bool {
+	if !retry.Retryable(r.StatusCode, err) {
+		return false
+	}
+
+	requestedURL := s.requestedURL(r.Request)
+	target, parseErr := url.Parse(requestedURL)
+	if parseErr != nil {
+		return false
+	}
+
+	cfg := s.config.RetryPolicy(target.Host)
+	policy := retry.Policy{
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: cfg.InitialBackoff,
+		MaxBackoff:     cfg.MaxBackoff,
+		MaxRetryAfter:  cfg.MaxRetryAfter,
+	}
+	attempt := s.attempt(requestedURL) + 1
+	if attempt > policy.MaxAttempts {
+		return false
+	}
+
+	var headers http.Header
+	if r.Headers != nil {
+		headers = *r.Headers
+	}
+	delay, ok := policy.Backoff(attempt, headers)
+	if !ok {
+		log.Printf("Not retrying %s: Retry-After is longer than max-retry-after (%s)", requestedURL, policy.MaxRetryAfter)
+		return false
+	}
+	if s.config.Debug {
+		log.Printf("Retrying %s in %s (attempt %d of %d): %v", requestedURL, delay, attempt, policy.MaxAttempts, err)
+	}
+	if err := retry.Sleep(s.ctx, delay); err != nil {
+		return true
+	}
+
+	// Retry the URL as requested rather than where it redirected to
+	r.Request.URL = target
+	s.attempts.Store(requestedURL, attempt)
+	s.retrying.Store(requestedURL, struct{}{})
+	s.stats.IncrementRetried()
+	if err := r.Request.Retry(); err != nil {
+		s.retrying.Delete(requestedURL)
+		if s.stopping() {
+			return true
+		}
+		log.Printf("Error retrying %s: %v", requestedURL, err)
+		return false
+	}
+	return true
+}
+
+// attempt returns how many times a URL has been requested so far
+func (s *Scraper) attempt(u string) int {
+	if attempt, ok := s.attempts.Load(u); ok {
+		return attempt.(int)
+	}
+	return 1
+}
@@ -0,0 +1,98 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy decides whether and when a failed request is retried
+type Policy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	MaxRetryAfter  time.Duration
+}
+
+// Retryable reports whether a request that failed with the given status code
+// and error may succeed when tried again. A status code of zero means the
+// request failed before a response was received.
+func Retryable(statusCode int, err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	switch {
+	case statusCode == 0:
+		return err != nil
+	case statusCode == http.StatusTooManyRequests:
+		return true
+	case statusCode >= 500:
+		return statusCode != http.StatusNotImplemented && statusCode != http.StatusHTTPVersionNotSupported
+	}
+	return false
+}
+
+// Backoff returns how long to wait before the given attempt, counting the
+// first request as attempt 1. The exponential delay is jittered between half
+// and all of its value and capped at MaxBackoff, but a longer Retry-After
+// header is honored up to MaxRetryAfter. It reports false when the server
+// asks to wait longer than that, in which case the request is not retried.
+func (p Policy) Backoff(attempt int, headers http.Header) (time.Duration, bool) {
+	delay := p.InitialBackoff
+	for i := 2; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if delay > 0 {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+
+	retryAfter := RetryAfter(headers, time.Now())
+	if retryAfter > p.MaxRetryAfter {
+		return 0, false
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay, true
+}
+
+// RetryAfter parses a Retry-After header given either in seconds or as an
+// HTTP date, returning zero when it is missing or invalid
+func RetryAfter(headers http.Header, now time.Time) time.Duration {
+	if headers == nil {
+		return 0
+	}
+	value := strings.TrimSpace(headers.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// Sleep waits for d or until ctx is cancelled
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
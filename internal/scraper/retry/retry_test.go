@@ -0,0 +1,107 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	failed := errors.New("connection reset")
+
+	tests := []struct {
+		name       string
+		statusCode int
+		err        error
+		want       bool
+	}{
+		{name: "network error", statusCode: 0, err: failed, want: true},
+		{name: "cancelled", statusCode: 0, err: context.Canceled, want: false},
+		{name: "too many requests", statusCode: http.StatusTooManyRequests, err: failed, want: true},
+		{name: "server error", statusCode: http.StatusBadGateway, err: failed, want: true},
+		{name: "not implemented", statusCode: http.StatusNotImplemented, err: failed, want: false},
+		{name: "not found", statusCode: http.StatusNotFound, err: failed, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Retryable(tt.statusCode, tt.err); got != tt.want {
+				t.Errorf("Retryable(%d, %v) = %v, want %v", tt.statusCode, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "missing", value: "", want: 0},
+		{name: "seconds", value: "120", want: 2 * time.Minute},
+		{name: "negative seconds", value: "-5", want: 0},
+		{name: "date", value: "Wed, 01 May 2024 12:00:30 GMT", want: 30 * time.Second},
+		{name: "past date", value: "Wed, 01 May 2024 11:00:00 GMT", want: 0},
+		{name: "invalid", value: "soon", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tt.value != "" {
+				headers.Set("Retry-After", tt.value)
+			}
+			if got := RetryAfter(headers, now); got != tt.want {
+				t.Errorf("RetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     4 * time.Second,
+		MaxRetryAfter:  time.Minute,
+	}
+
+	tests := []struct {
+		name       string
+		attempt    int
+		retryAfter string
+		min, max   time.Duration
+		retry      bool
+	}{
+		{name: "second attempt", attempt: 2, min: 500 * time.Millisecond, max: time.Second, retry: true},
+		{name: "third attempt", attempt: 3, min: time.Second, max: 2 * time.Second, retry: true},
+		{name: "capped", attempt: 10, min: 2 * time.Second, max: 4 * time.Second, retry: true},
+		{name: "shorter Retry-After", attempt: 3, retryAfter: "1", min: time.Second, max: 2 * time.Second, retry: true},
+		{name: "longer Retry-After", attempt: 2, retryAfter: "30", min: 30 * time.Second, max: 30 * time.Second, retry: true},
+		{name: "Retry-After at the cap", attempt: 2, retryAfter: "60", min: time.Minute, max: time.Minute, retry: true},
+		{name: "Retry-After beyond the cap", attempt: 2, retryAfter: "3600", retry: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tt.retryAfter != "" {
+				headers.Set("Retry-After", tt.retryAfter)
+			}
+			for i := 0; i < 20; i++ {
+				delay, retry := policy.Backoff(tt.attempt, headers)
+				if retry != tt.retry {
+					t.Fatalf("Backoff(%d) retries = %v, want %v", tt.attempt, retry, tt.retry)
+				}
+				if retry && (delay < tt.min || delay > tt.max) {
+					t.Fatalf("Backoff(%d) = %s, want between %s and %s", tt.attempt, delay, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package robots
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// Directives holds the page level robots directives of a document
+type Directives struct {
+	NoIndex  bool
+	NoFollow bool
+}
+
+// Checker fetches, caches and evaluates robots.txt rules per host
+type Checker struct {
+	client    *http.Client
+	userAgent string
+	cache     map[string]*entry
+	mutex     sync.Mutex
+}
+
+// entry is the robots.txt data of a host, available once loaded is closed
+type entry struct {
+	loaded chan struct{}
+	data   *robotstxt.RobotsData
+}
+
+// NewChecker creates a new robots.txt checker for the given user agent
+func NewChecker(userAgent string) *Checker {
+	return &Checker{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		userAgent: userAgent,
+		cache:     make(map[string]*entry),
+	}
+}
+
+// Allowed reports whether the user agent may fetch the given URL. The
+// robots.txt of the host is fetched with ctx the first time it is needed.
+func (c *Checker) Allowed(ctx context.Context, u *url.URL) bool {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return c.group(ctx, u).Test(path)
+}
+
+// CrawlDelay returns the Crawl-delay requested for the URL's host
+func (c *Checker) CrawlDelay(ctx context.Context, u *url.URL) time.Duration {
+	return c.group(ctx, u).CrawlDelay
+}
+
+// group returns the robots.txt group that applies to the user agent
+func (c *Checker) group(ctx context.Context, u *url.URL) *robotstxt.Group {
+	return c.load(ctx, u).FindGroup(c.userAgent)
+}
+
+// load returns the cached robots.txt data for a host. The first request for
+// a host fetches it while concurrent requests wait for that fetch. A fetch
+// cut short by ctx allows everything and is not cached.
+func (c *Checker) load(ctx context.Context, u *url.URL) *robotstxt.RobotsData {
+	key := u.Scheme + "://" + u.Host
+
+	c.mutex.Lock()
+	e, ok := c.cache[key]
+	if !ok {
+		e = &entry{loaded: make(chan struct{})}
+		c.cache[key] = e
+	}
+	c.mutex.Unlock()
+
+	if ok {
+		select {
+		case <-e.loaded:
+			return e.data
+		case <-ctx.Done():
+			return allowAll()
+		}
+	}
+
+	e.data = c.fetch(ctx, key+"/robots.txt")
+	if ctx.Err() != nil {
+		c.mutex.Lock()
+		delete(c.cache, key)
+		c.mutex.Unlock()
+	}
+	close(e.loaded)
+	return e.data
+}
+
+// allowAll returns robots.txt data allowing everything
+func allowAll() *robotstxt.RobotsData {
+	data, _ := robotstxt.FromStatusAndBytes(http.StatusNotFound, nil)
+	return data
+}
+
+// fetch downloads and parses a robots.txt file, allowing everything when
+// the file cannot be retrieved or parsed
+func (c *Checker) fetch(ctx context.Context, robotsURL string) *robotstxt.RobotsData {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return allowAll()
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return allowAll()
+	}
+	defer resp.Body.Close()
+
+	data, err := robotstxt.FromResponse(resp)
+	if err != nil || data == nil {
+		return allowAll()
+	}
+	return data
+}
+
+// ParseDirectives parses a comma separated robots directive list such as the
+// content of a robots meta tag or an X-Robots-Tag header
+func ParseDirectives(values ...string) Directives {
+	var d Directives
+	for _, value := range values {
+		for _, directive := range strings.Split(value, ",") {
+			switch strings.ToLower(strings.TrimSpace(directive)) {
+			case "noindex":
+				d.NoIndex = true
+			case "nofollow":
+				d.NoFollow = true
+			case "none":
+				d.NoIndex = true
+				d.NoFollow = true
+			}
+		}
+	}
+	return d
+}
+
+// HasNoFollow reports whether a link rel attribute contains nofollow
+func HasNoFollow(rel string) bool {
+	for _, value := range strings.Fields(strings.ToLower(rel)) {
+		if value == "nofollow" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package robots
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseDirectives(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   Directives
+	}{
+		{name: "empty", values: nil, want: Directives{}},
+		{name: "noindex", values: []string{"noindex"}, want: Directives{NoIndex: true}},
+		{name: "list", values: []string{"NoIndex, nofollow"}, want: Directives{NoIndex: true, NoFollow: true}},
+		{name: "none", values: []string{"none"}, want: Directives{NoIndex: true, NoFollow: true}},
+		{name: "several values", values: []string{"index", "nofollow"}, want: Directives{NoFollow: true}},
+		{name: "unknown", values: []string{"noarchive, max-snippet:50"}, want: Directives{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseDirectives(tt.values...); got != tt.want {
+				t.Errorf("ParseDirectives() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasNoFollow(t *testing.T) {
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{rel: "", want: false},
+		{rel: "nofollow", want: true},
+		{rel: "noopener NoFollow", want: true},
+		{rel: "nofollower", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rel, func(t *testing.T) {
+			if got := HasNoFollow(tt.rel); got != tt.want {
+				t.Errorf("HasNoFollow(%q) = %v, want %v", tt.rel, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecker(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		fetches.Add(1)
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\nDisallow: /search?q=\nCrawl-delay: 2\n\nUser-agent: bullnose\nDisallow: /drafts\n")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		userAgent string
+		path      string
+		want      bool
+	}{
+		{name: "allowed", userAgent: "other", path: "/docs", want: true},
+		{name: "disallowed", userAgent: "other", path: "/private/page", want: false},
+		{name: "disallowed query", userAgent: "other", path: "/search?q=go", want: false},
+		{name: "root", userAgent: "other", path: "", want: true},
+		{name: "own group", userAgent: "bullnose", path: "/drafts/1", want: false},
+		{name: "own group only", userAgent: "bullnose", path: "/private/page", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(server.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := NewChecker(tt.userAgent).Allowed(context.Background(), u); got != tt.want {
+				t.Errorf("Allowed(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("crawl delay", func(t *testing.T) {
+		u, _ := url.Parse(server.URL + "/docs")
+		if got := NewChecker("other").CrawlDelay(context.Background(), u); got != 2*time.Second {
+			t.Errorf("CrawlDelay() = %s, want 2s", got)
+		}
+	})
+
+	t.Run("fetched once", func(t *testing.T) {
+		fetches.Store(0)
+		checker := NewChecker("other")
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				u, _ := url.Parse(fmt.Sprintf("%s/page%d", server.URL, i))
+				checker.Allowed(context.Background(), u)
+			}(i)
+		}
+		wg.Wait()
+		if got := fetches.Load(); got != 1 {
+			t.Errorf("robots.txt fetched %d times, want 1", got)
+		}
+	})
+}
+
+func TestCheckerUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/private")
+	if !NewChecker("other").Allowed(context.Background(), u) {
+		t.Error("Allowed() = false without a robots.txt, want true")
+	}
+}
@@ -0,0 +1,219 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ncecere/bullnose/internal/config"
+	"github.com/ncecere/bullnose/internal/scraper/storage"
+	"github.com/ncecere/bullnose/internal/utils"
+)
+
+// anyURL is the filter used when a crawl is not scoped to parts of sites
+var anyURL = regexp.MustCompile(`^https?://[^/]+(?:/.*)?$`)
+
+// seed is a starting URL of the crawl with the rules that apply to the
+// pages discovered from it
+type seed struct {
+	url      string // normalized
+	depth    int
+	sitemaps bool
+	profile  string           // content-patterns entry to extract with, empty for the page's domain
+	domains  []string         // hosts the crawl may reach, empty for any
+	scope    []*regexp.Regexp // URLs the crawl may reach
+	ignore   []*regexp.Regexp
+	storage  *storage.Storage
+}
+
+// newSeed resolves the settings of a configured seed. Pages are stored by
+// the storage of its output directory, created by open the first time.
+func newSeed(cfg *config.Config, configured config.Seed, urls *utils.URLNormalizer, open func(dir string) *storage.Storage) (*seed, error) {
+	settings := cfg.ResolveSeed(configured)
+	normalized, err := urls.Normalize(settings.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", settings.URL, err)
+	}
+
+	sd := &seed{
+		url:      normalized,
+		depth:    settings.Depth,
+		sitemaps: *settings.ParseSitemaps,
+		profile:  settings.ExtractionProfile,
+		storage:  open(settings.Output),
+	}
+
+	if *settings.RestrictDomain {
+		sd.domains, err = utils.GetAllowedDomains([]string{normalized})
+		if err != nil {
+			return nil, fmt.Errorf("error getting allowed domains: %w", err)
+		}
+	}
+	if sd.scope, err = scopeFilters(normalized, settings.Include, *settings.RestrictPath); err != nil {
+		return nil, err
+	}
+	if sd.ignore, err = utils.CreateURLFilters(settings.Ignore); err != nil {
+		return nil, fmt.Errorf("error creating URL filters: %w", err)
+	}
+	return sd, nil
+}
+
+// scopeFilters returns the filters of the URLs a seed's crawl visits: the
+// seed itself, URLs matching an include pattern and, with restrictPath,
+// the URLs under the path of the seed. Without include patterns or
+// restrictPath every http and https URL is.
+func scopeFilters(seedURL string, include []string, restrictPath bool) ([]*regexp.Regexp, error) {
+	if len(include) == 0 && !restrictPath {
+		return []*regexp.Regexp{anyURL}, nil
+	}
+
+	filters, err := utils.CreateIncludeFilters(include)
+	if err != nil {
+		return nil, err
+	}
+	filters = append(filters, regexp.MustCompile("^"+regexp.QuoteMeta(seedURL)+"$"))
+
+	if restrictPath {
+		filter, err := utils.SeedPathFilter(seedURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL %s: %w", seedURL, err)
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// allows reports whether a normalized URL is within the crawl of the seed
+func (sd *seed) allows(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return false
+	}
+	if len(sd.domains) > 0 && !contains(sd.domains, strings.ToLower(parsed.Hostname())) {
+		return false
+	}
+	for _, filter := range sd.ignore {
+		if filter.MatchString(u) {
+			return false
+		}
+	}
+	for _, filter := range sd.scope {
+		if filter.MatchString(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// setupSeeds resolves the configured seeds and gives the collector the
+// rules every seed shares, so it rejects what no seed would crawl. The
+// rules of each seed are applied when its links are queued.
+func (s *Scraper) setupSeeds(layout storage.Layout) error {
+	storages := make(map[string]*storage.Storage)
+	open := func(dir string) *storage.Storage {
+		key := filepath.Clean(dir)
+		if key == filepath.Clean(s.config.Output) {
+			return s.storage
+		}
+		if _, ok := storages[key]; !ok {
+			storages[key] = storage.New(dir, layout, s.config.RescrapeAfter, s.config.Force)
+		}
+		return storages[key]
+	}
+
+	restricted := true
+	var domains []string
+	var scope []*regexp.Regexp
+	for i, configured := range s.config.URLs {
+		sd, err := newSeed(s.config, configured, s.urls, open)
+		if err != nil {
+			return err
+		}
+		s.seeds = append(s.seeds, sd)
+		if !contains(s.outputs, sd.storage) {
+			s.outputs = append(s.outputs, sd.storage)
+		}
+
+		if len(sd.domains) == 0 {
+			restricted = false
+		}
+		domains = append(domains, sd.domains...)
+		scope = append(scope, sd.scope...)
+
+		// Only patterns every seed ignores can be left to the collector
+		if i == 0 {
+			s.collector.DisallowedURLFilters = sd.ignore
+		} else {
+			s.collector.DisallowedURLFilters = commonFilters(s.collector.DisallowedURLFilters, sd.ignore)
+		}
+	}
+
+	if restricted {
+		s.collector.AllowedDomains = domains
+	}
+	s.collector.URLFilters = scope
+	return nil
+}
+
+// commonFilters returns the filters compiled from the same expression as
+// one of others
+func commonFilters(filters, others []*regexp.Regexp) []*regexp.Regexp {
+	var common []*regexp.Regexp
+	for _, filter := range filters {
+		for _, other := range others {
+			if other.String() == filter.String() {
+				common = append(common, filter)
+				break
+			}
+		}
+	}
+	return common
+}
+
+// seedOf returns the seed a URL in the frontier was discovered from. URLs
+// are added to the frontier with their seed before they are requested, and
+// resumed frontiers are checked against the configured seeds.
+func (s *Scraper) seedOf(u string) *seed {
+	entry, _ := s.frontier.Get(u)
+	sd := s.seed(entry.Seed)
+	if sd == nil {
+		panic(fmt.Sprintf("no seed recorded for %s", u))
+	}
+	return sd
+}
+
+// seed returns the configured seed with a normalized URL, or nil
+func (s *Scraper) seed(u string) *seed {
+	for _, sd := range s.seeds {
+		if sd.url == u {
+			return sd
+		}
+	}
+	return nil
+}
+
+// inScope reports whether any seed would crawl a URL once normalized
+func (s *Scraper) inScope(rawURL string) bool {
+	normalized, err := s.urls.Normalize(rawURL)
+	if err != nil {
+		return false
+	}
+	for _, sd := range s.seeds {
+		if sd.allows(normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether a slice contains a value
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,19 +1,29 @@
 package scraper
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"regexp"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gocolly/colly/v2"
 
 	"github.com/ncecere/bullnose/internal/config"
+	"github.com/ncecere/bullnose/internal/scraper/assets"
+	"github.com/ncecere/bullnose/internal/scraper/chunker"
 	"github.com/ncecere/bullnose/internal/scraper/content"
+	"github.com/ncecere/bullnose/internal/scraper/document"
+	"github.com/ncecere/bullnose/internal/scraper/frontier"
+	"github.com/ncecere/bullnose/internal/scraper/ratelimit"
+	"github.com/ncecere/bullnose/internal/scraper/robots"
 	"github.com/ncecere/bullnose/internal/scraper/sitemap"
 	"github.com/ncecere/bullnose/internal/scraper/stats"
 	"github.com/ncecere/bullnose/internal/scraper/storage"
+	"github.com/ncecere/bullnose/internal/scraper/throttle"
 	"github.com/ncecere/bullnose/internal/utils"
 )
 
@@ -22,192 +32,799 @@ type Scraper struct {
 	config    *config.Config
 	collector *colly.Collector
 	stats     *stats.Stats
-	storage   *storage.Storage
+	budget    budget
+	storage   *storage.Storage   // visited URLs and crawl state, and pages of seeds without their own output
+	outputs   []*storage.Storage // storage of every output directory with seeds
+	seeds     []*seed
+	urls      *utils.URLNormalizer
 	extractor *content.Extractor
+	documents *document.Renderer
+	chunker   *chunker.Chunker
+	images    *assets.Downloader
+	robots    *robots.Checker
+	pacer     *ratelimit.Pacer
+	throttle  *throttle.Throttle
+	limits    []domainLimit
+	frontier  *frontier.Frontier
+	requested sync.Map // request ID -> URL as originally requested
+	attempts  sync.Map // URL -> number of requests made for it
+	retrying  sync.Map // URLs with a retry about to be requested
+	saved     sync.Map // image URL -> path of a downloaded copy
+
+	ctx           context.Context    // cancelled when the crawl should stop
+	abortRequests context.CancelFunc // aborts in-flight requests
 }
 
 // New creates a new Scraper instance
 func New(cfg *config.Config) (*Scraper, error) {
-	// Create collector with configuration
+	// Create collector with configuration. colly's Async option always makes
+	// the collector asynchronous, so parallelism comes from the limit rules.
 	c := colly.NewCollector(
-		colly.MaxDepth(cfg.Depth),
-		colly.Async(cfg.Parallel > 1),
-		colly.URLFilters(
-			regexp.MustCompile(`^https?://[^/]+(?:/.*)?$`), // Allow base domain and paths
-		),
+		colly.UserAgent(cfg.UserAgent),
+		colly.Async(true),
 	)
 
-	// Set parallel limit if enabled
-	if cfg.Parallel > 1 {
-		c.Limit(&colly.LimitRule{
-			DomainGlob:  "*",
-			Parallelism: cfg.Parallel,
-			RandomDelay: 1 * time.Second,
-		})
+	// Domain rules come first since colly applies the first matching rule
+	limits := domainLimits(cfg)
+	for _, limit := range limits {
+		if err := c.Limit(limit.rule); err != nil {
+			return nil, fmt.Errorf("error setting rate limit for %s: %w", limit.pattern, err)
+		}
 	}
 
-	// Configure domain restriction if enabled
-	if cfg.RestrictDomain && len(cfg.URLs) > 0 {
-		domains, err := utils.GetAllowedDomains(cfg.URLs)
-		if err != nil {
-			return nil, fmt.Errorf("error getting allowed domains: %w", err)
-		}
-		c.AllowedDomains = domains
+	// The global rule bounds every other domain to the parallel setting
+	if err := c.Limit(&colly.LimitRule{
+		DomainGlob:  "*",
+		Parallelism: cfg.Parallel,
+		RandomDelay: globalRandomDelay(cfg),
+	}); err != nil {
+		return nil, fmt.Errorf("error setting rate limit: %w", err)
+	}
+
+	urls, err := utils.NewURLNormalizer(cfg.Normalize.DropParams, cfg.Normalize.SortQuery,
+		cfg.Normalize.IgnoreScheme, cfg.Normalize.IgnoreTrailingSlash)
+	if err != nil {
+		return nil, err
 	}
 
-	// Configure URL filters
-	filters, err := utils.CreateURLFilters(cfg.Ignore)
+	layout, err := storage.ParseLayout(cfg.OutputLayout)
 	if err != nil {
-		return nil, fmt.Errorf("error creating URL filters: %w", err)
+		return nil, err
+	}
+
+	frontMatter, err := document.ParseFormat(cfg.FrontMatter)
+	if err != nil {
+		return nil, err
+	}
+	documents, err := document.NewRenderer(frontMatter, cfg.HeaderTemplate, cfg.FrontMatterFields)
+	if err != nil {
+		return nil, err
 	}
-	c.DisallowedURLFilters = filters
 
 	// Initialize components
 	s := &Scraper{
 		config:    cfg,
 		collector: c,
 		stats:     stats.New(),
-		storage:   storage.New(cfg.Output, cfg.RescrapeAfter, cfg.Force),
-		extractor: content.NewExtractor(convertContentPatterns(cfg.ContentPatterns)),
+		storage:   storage.New(cfg.Output, layout, cfg.RescrapeAfter, cfg.Force),
+		urls:      urls,
+		extractor: content.NewExtractor(convertContentPatterns(cfg.ContentPatterns), cfg.LinksSection),
+		documents: documents,
+		pacer:     ratelimit.NewPacer(),
+		limits:    limits,
+	}
+	for _, limit := range limits {
+		s.stats.AddDomain(limit.pattern, limit.String())
+	}
+	s.frontier = frontier.New(s.storage.StatePath(frontierFile))
+
+	// Configure the domain restriction, URL filters and output of each seed
+	if err := s.setupSeeds(layout); err != nil {
+		return nil, err
+	}
+
+	// Allow in-flight requests to be aborted during shutdown
+	s.ctx = context.Background()
+	requestCtx, abortRequests := context.WithCancel(context.Background())
+	s.abortRequests = abortRequests
+	var transport http.RoundTripper = &cancelTransport{base: http.DefaultTransport, ctx: requestCtx}
+
+	// Slow down hosts that start throttling the crawl
+	if cfg.Throttle.Enabled {
+		s.throttle = throttle.New(cfg.Throttle.MaxDelay, cfg.Throttle.RecoverAfter, s.maxParallel)
+		transport = &throttleTransport{base: transport, scraper: s}
+	}
+
+	// Skip pages over the size limit, which colly would truncate instead
+	c.MaxBodySize = 0
+	if cfg.MaxPageSize > 0 {
+		c.WithTransport(&pageSizeTransport{base: transport, maxSize: cfg.MaxPageSize})
+	} else {
+		c.WithTransport(transport)
+	}
+
+	// Split pages into chunks for embedding pipelines when enabled
+	if cfg.Chunks.Enabled {
+		unit, err := chunker.ParseUnit(cfg.Chunks.Unit)
+		if err != nil {
+			return nil, err
+		}
+		s.chunker = chunker.New(cfg.Chunks.Size, cfg.Chunks.Overlap, unit)
+	}
+
+	// Download images next to the markdown when enabled
+	if cfg.Images.Download {
+		s.images = assets.NewDownloader(transport, cfg.Images.MaxSize, s.prepareImageRequest)
+	}
+
+	// Load records of previous runs for incremental scraping
+	for _, output := range s.outputs {
+		if err := output.LoadRecords(); err != nil {
+			return nil, fmt.Errorf("error loading records: %w", err)
+		}
+		if cfg.Manifest {
+			output.EnableManifest(cfg.ManifestCSV)
+		}
+		if cfg.Dedup.Enabled {
+			output.EnableDedup(cfg.Dedup.NearDuplicates, cfg.Dedup.MaxDistance)
+		}
+	}
+
+	// Honor robots.txt if enabled
+	if cfg.RespectRobots {
+		s.robots = robots.NewChecker(cfg.UserAgent)
 	}
 
 	s.setupCallbacks()
 	return s, nil
 }
 
-// Start begins the scraping process
-func (s *Scraper) Start() error {
-	// Process sitemaps if enabled
-	if s.config.ParseSitemaps {
-		sitemapParser := sitemap.NewParser()
-		for _, baseURL := range s.config.URLs {
-			sitemapURLs, err := utils.GetCommonSitemapURLs(baseURL)
+// Start begins the scraping process and blocks until it completes or ctx is
+// cancelled. A cancelled crawl saves its state, prints its statistics and
+// returns ErrStopped, and a crawl that reached one of its limits does the
+// same but returns ErrLimitReached.
+func (s *Scraper) Start(ctx context.Context) error {
+	// Limits stop the crawl by cancelling its own context, leaving ctx to
+	// tell an interrupted crawl from one that reached a limit
+	crawlCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.ctx = crawlCtx
+	s.budget.cancel = cancel
+	stopCheckpoints := s.startCheckpoints()
+
+	// Stop once the time budget is spent
+	if s.config.MaxDuration > 0 {
+		timer := time.AfterFunc(s.config.MaxDuration, func() {
+			s.reachLimit("max-duration", halt)
+		})
+		defer timer.Stop()
+	}
+
+	resumed, err := s.resume()
+	if err != nil {
+		stopCheckpoints()
+		return err
+	}
+	if !resumed {
+		if s.frontier.Exists() {
+			log.Printf("Found an interrupted crawl in %s; use --resume to continue it", s.config.Output)
+		}
+		if err := s.visitSeeds(); err != nil && !s.stopping() {
+			stopCheckpoints()
+			return err
+		}
+	}
+
+	s.wait(ctx)
+	stopCheckpoints()
+	stopped := ctx.Err() != nil
+	limit := s.limitReached()
+
+	// Name pages with colliding paths the same way whatever order they
+	// were saved in
+	for _, output := range s.outputs {
+		if err := output.SettlePaths(); err != nil {
+			return fmt.Errorf("error settling output paths: %w", err)
+		}
+	}
+
+	// Point links between saved pages at their local files
+	if s.config.RewriteLinks {
+		if err := s.rewriteLinks(); err != nil {
+			return fmt.Errorf("error rewriting links: %w", err)
+		}
+	}
+
+	// Persist records for the next incremental run
+	for _, output := range s.outputs {
+		if err := output.SaveRecords(); err != nil {
+			return fmt.Errorf("error saving records: %w", err)
+		}
+		if err := output.WriteManifest(); err != nil {
+			return fmt.Errorf("error writing manifest: %w", err)
+		}
+	}
+
+	if stopped || limit != "" {
+		// Keep the frontier so the crawl can be resumed
+		if err := s.frontier.Save(); err != nil {
+			return fmt.Errorf("error saving crawl state: %w", err)
+		}
+		if stopped {
+			s.stats.Stop("interrupted")
+		}
+	} else if err := s.frontier.Remove(); err != nil {
+		// The crawl is complete, so there is nothing left to resume
+		return fmt.Errorf("error removing crawl state: %w", err)
+	}
+
+	// Print statistics
+	fmt.Print(s.stats.GetSummary())
+
+	if stopped {
+		return ErrStopped
+	}
+	if limit != "" {
+		return ErrLimitReached
+	}
+	return nil
+}
+
+// visitSeeds queues the configured URLs and any URLs listed in their sitemaps
+func (s *Scraper) visitSeeds() error {
+	// Claim the seeds before any page is queued, so a seed found from
+	// another seed keeps its own settings
+	var seeds []*seed
+	for _, sd := range s.seeds {
+		if sd.allows(sd.url) && s.frontier.Add(sd.url, 1, "", sd.url) {
+			seeds = append(seeds, sd)
+		}
+	}
+
+	// Process sitemaps of the seeds parsing them
+	sitemapParser := sitemap.NewParser()
+	for _, sd := range s.seeds {
+		if !sd.sitemaps {
+			continue
+		}
+		sitemapURLs, err := utils.GetCommonSitemapURLs(sd.url)
+		if err != nil {
+			log.Printf("Error getting sitemap URLs for %s: %v", sd.url, err)
+			continue
+		}
+
+		for _, sitemapURL := range sitemapURLs {
+			if s.stopping() {
+				return nil
+			}
+			urls, err := sitemapParser.Parse(sitemapURL)
 			if err != nil {
-				log.Printf("Error getting sitemap URLs for %s: %v", baseURL, err)
+				if s.config.Debug {
+					log.Printf("Error parsing sitemap %s: %v", sitemapURL, err)
+				}
 				continue
 			}
 
-			for _, sitemapURL := range sitemapURLs {
-				urls, err := sitemapParser.Parse(sitemapURL)
-				if err != nil {
-					if s.config.Debug {
-						log.Printf("Error parsing sitemap %s: %v", sitemapURL, err)
-					}
-					continue
-				}
-
-				// Add discovered URLs to the scraping queue
-				for _, u := range urls {
-					if !s.storage.IsVisited(u) {
-						if err := s.collector.Visit(u); err != nil &&
-							err != colly.ErrAlreadyVisited &&
-							s.config.Debug {
-							log.Printf("Error visiting %s: %v", u, err)
-						}
-					}
+			// Add discovered URLs to the scraping queue
+			for _, u := range urls {
+				if err := s.visit(nil, u, 1, "", sd); err != nil && s.config.Debug {
+					log.Printf("Error visiting %s: %v", u, err)
 				}
 			}
 		}
 	}
 
 	// Process regular URLs
-	for _, u := range s.config.URLs {
-		if err := s.collector.Visit(u); err != nil && err != colly.ErrAlreadyVisited {
-			return fmt.Errorf("error visiting %s: %w", u, err)
+	for _, sd := range seeds {
+		if err := s.request(nil, sd.url); err != nil {
+			return fmt.Errorf("error visiting %s: %w", sd.url, err)
 		}
 	}
 
-	s.collector.Wait()
+	return nil
+}
 
-	// Print statistics
-	fmt.Print(s.stats.GetSummary())
+// visit queues a URL discovered at the given depth, either from a page being
+// processed or, when from is nil, as a starting point of the crawl. The URL
+// is normalized first, and URLs already visited under any spelling, already
+// in the frontier, deeper than the depth of the seed it was discovered from
+// or outside the seed's crawl are ignored.
+func (s *Scraper) visit(from *colly.Request, u string, depth int, parent string, sd *seed) error {
+	normalized, err := s.urls.Normalize(u)
+	if err != nil {
+		return err
+	}
+	u = normalized
+	if depth > sd.depth || !sd.allows(u) || s.visited(u) || !s.frontier.Add(u, depth, parent, sd.url) {
+		return nil
+	}
+	return s.request(from, u)
+}
 
-	return nil
+// request starts the request for a URL added to the frontier, from the page
+// being processed or, when from is nil, from the collector
+func (s *Scraper) request(from *colly.Request, u string) error {
+	var err error
+	if from != nil {
+		err = from.Visit(u)
+	} else {
+		err = s.collector.Visit(u)
+	}
+	if err != nil && !s.stopping() {
+		// The request never started, so no callback will complete it
+		s.frontier.MarkDone(u)
+		if err == colly.ErrAlreadyVisited {
+			return nil
+		}
+	}
+	return err
+}
+
+// depth returns the crawl depth of a request
+func (s *Scraper) depth(r *colly.Request) int {
+	if entry, ok := s.frontier.Get(s.requestedURL(r)); ok {
+		return entry.Depth
+	}
+	return r.Depth
 }
 
 func (s *Scraper) setupCallbacks() {
 	// Set up custom headers and cookies for each request
 	s.collector.OnRequest(func(r *colly.Request) {
-		if s.storage.IsVisited(r.URL.String()) {
+		// Retries request a URL that has already been visited
+		_, retrying := s.retrying.LoadAndDelete(r.URL.String())
+
+		// Leave queued URLs pending in the frontier once stopping. Retries
+		// hold their page already, so a draining crawl still makes them.
+		if s.ctx.Err() != nil || (s.stopping() && !retrying) {
 			r.Abort()
 			return
 		}
-		s.storage.MarkVisited(r.URL.String())
 
-		s.stats.IncrementScanned()
-		if s.config.Debug {
-			log.Printf("Visiting %s", r.URL)
+		if !retrying {
+			if s.visited(r.URL.String()) {
+				s.frontier.MarkDone(r.URL.String())
+				r.Abort()
+				return
+			}
+			s.markVisited(r.URL.String())
 		}
 
-		// Add domain-specific headers and cookies
-		if domainCfg, ok := s.config.DomainConfig[r.URL.Host]; ok {
-			for key, value := range domainCfg.Headers {
-				r.Headers.Set(key, value)
+		// Skip pages scraped recently, following their stored links instead
+		output := s.seedOf(r.URL.String()).storage
+		if !output.ShouldRescrape(r.URL.String()) {
+			s.stats.IncrementSkipped()
+			if s.config.Debug {
+				log.Printf("Skipping recently scraped %s", r.URL)
 			}
-			for key, value := range domainCfg.Cookies {
-				r.Headers.Set("Cookie", fmt.Sprintf("%s=%s", key, value))
+			s.expandStoredLinks(r)
+			s.frontier.MarkDone(r.URL.String())
+			r.Abort()
+			return
+		}
+
+		// Skip URLs disallowed by robots.txt
+		if s.robots != nil && !s.robots.Allowed(s.ctx, r.URL) {
+			s.stats.IncrementBlocked()
+			if s.config.Debug {
+				log.Printf("Blocked by robots.txt: %s", r.URL)
 			}
+			s.frontier.MarkDone(r.URL.String())
+			r.Abort()
+			return
+		}
+
+		// Stay within the page limits, leaving the URL pending for --resume
+		if !retrying && !s.takePage(r.URL) {
+			r.Abort()
+			return
+		}
+
+		// Honor Crawl-delay and the requests-per-second cap of the matching
+		// domain limit. A page whose request never goes out is given back.
+		if err := s.pace(r.URL); err != nil {
+			if !retrying {
+				s.releasePage(r.URL)
+			}
+			r.Abort()
+			return
 		}
-	})
 
-	// Set up link following
-	s.collector.OnHTML("a[href]", func(e *colly.HTMLElement) {
-		link := e.Attr("href")
+		if !retrying {
+			s.stats.IncrementScanned()
+		}
 		if s.config.Debug {
-			log.Printf("Found link: %s", link)
+			log.Printf("Visiting %s", r.URL)
 		}
-		if !s.storage.IsVisited(e.Request.AbsoluteURL(link)) {
-			e.Request.Visit(link)
+
+		// Add domain-specific headers and cookies
+		s.setDomainHeaders(r.URL.Host, *r.Headers)
+
+		// Revalidate stale pages instead of downloading them again
+		etag, lastModified := output.Validators(r.URL.String())
+		if etag != "" {
+			r.Headers.Set("If-None-Match", etag)
+		}
+		if lastModified != "" {
+			r.Headers.Set("If-Modified-Since", lastModified)
 		}
+
+		s.requested.Store(r.ID, r.URL.String())
 	})
 
 	s.collector.OnHTML("html", func(e *colly.HTMLElement) {
-		// Extract title
-		title := s.extractor.ExtractTitle(
-			e.Request.URL.Host,
-			e.DOM,
-			e.Request.URL.Path,
-		)
-
-		// Extract content
-		content := s.extractor.ExtractContent(e.Request.URL.Host, e.DOM)
-
-		// Generate markdown content with consistent formatting
-		var markdown strings.Builder
-		markdown.WriteString(fmt.Sprintf("# %s\n", title))
-		markdown.WriteString("\n## Metadata\n")
-		markdown.WriteString(fmt.Sprintf("- URL: %s\n", e.Request.URL.String()))
-		markdown.WriteString(fmt.Sprintf("- Scraped: %s\n", time.Now().UTC().Format(time.RFC3339)))
-		markdown.WriteString("\n## Content\n")
-		markdown.WriteString(content)
-
-		// Save content
-		outputPath, err := s.storage.SaveContent(e.Request.URL.Host, title, markdown.String())
-		if err != nil {
-			log.Printf("Error saving content for %s: %v", e.Request.URL, err)
-			return
+		// A redirect target needs no visit of its own
+		s.markVisited(e.Request.URL.String())
+
+		directives := s.pageDirectives(e)
+		requestedURL := s.requestedURL(e.Request)
+		entry, _ := s.frontier.Get(requestedURL)
+		sd := s.seedOf(requestedURL)
+		record := storage.Record{
+			URL:          requestedURL,
+			FinalURL:     e.Request.URL.String(),
+			StatusCode:   e.Response.StatusCode,
+			Depth:        s.depth(e.Request),
+			ParentURL:    entry.Parent,
+			FetchedAt:    time.Now().UTC(),
+			ETag:         e.Response.Headers.Get("ETag"),
+			LastModified: e.Response.Headers.Get("Last-Modified"),
 		}
 
-		s.stats.IncrementScraped()
+		// Follow links unless the page asks us not to
+		if directives.NoFollow {
+			if s.config.Debug {
+				log.Printf("Not following links on %s (nofollow)", e.Request.URL)
+			}
+		} else {
+			record.Links = s.followLinks(e, sd)
+		}
 
-		if s.config.Debug {
-			log.Printf("Scraped %s -> %s", e.Request.URL, outputPath)
+		// Save content unless the page asks not to be indexed
+		if directives.NoIndex {
+			s.stats.IncrementNoIndex()
+			if s.config.Debug {
+				log.Printf("Not saving %s (noindex)", e.Request.URL)
+			}
+			if err := sd.storage.PutRecord(record); err != nil {
+				log.Printf("Error storing record for %s: %v", e.Request.URL, err)
+			}
+			return
 		}
+		s.savePage(e, record, sd)
+	})
+
+	s.collector.OnScraped(func(r *colly.Response) {
+		s.frontier.MarkDone(s.requestedURL(r.Request))
+		s.attempts.Delete(s.requestedURL(r.Request))
+		s.requested.Delete(r.Request.ID)
 	})
 
 	s.collector.OnError(func(r *colly.Response, err error) {
+		defer s.requested.Delete(r.Request.ID)
+
+		// Requests aborted during shutdown stay pending for --resume
+		if s.stopping() && errors.Is(err, context.Canceled) {
+			if s.config.Debug {
+				log.Printf("Aborted %s", r.Request.URL)
+			}
+			return
+		}
+
+		// Pages over the size limit are skipped rather than retried
+		if errors.Is(err, errPageTooLarge) {
+			s.attempts.Delete(s.requestedURL(r.Request))
+			s.frontier.MarkDone(s.requestedURL(r.Request))
+			s.stats.IncrementTooLarge()
+			if s.config.Debug {
+				log.Printf("Not saving %s (%v)", r.Request.URL, err)
+			}
+			return
+		}
+
+		// Transient failures are retried before the page is given up on
+		if s.retryRequest(r, err) {
+			return
+		}
+		s.attempts.Delete(s.requestedURL(r.Request))
+		s.frontier.MarkDone(s.requestedURL(r.Request))
+
+		// A 304 means our stored copy is still current
+		if r.StatusCode == http.StatusNotModified {
+			touched, touchErr := s.seedOf(s.requestedURL(r.Request)).storage.Touch(s.requestedURL(r.Request))
+			if touchErr != nil {
+				log.Printf("Error storing record for %s: %v", r.Request.URL, touchErr)
+			}
+			if touched {
+				s.stats.IncrementUnchanged()
+				if s.config.Debug {
+					log.Printf("Unchanged %s", r.Request.URL)
+				}
+				s.expandStoredLinks(r.Request)
+				return
+			}
+		}
+
 		if err != colly.ErrAlreadyVisited {
+			s.stats.IncrementFailed()
 			log.Printf("Error scraping %s: %v", r.Request.URL, err)
 		}
 	})
 
 	s.collector.OnResponse(func(r *colly.Response) {
+		s.addBytes(len(r.Body))
 		if s.config.Debug {
 			log.Printf("Got response from %s: %d bytes", r.Request.URL, len(r.Body))
 		}
 	})
 }
 
+// setDomainHeaders adds the headers and cookies configured for a host
+func (s *Scraper) setDomainHeaders(host string, headers http.Header) {
+	_, domainCfg := s.config.Domain(host)
+	if domainCfg == nil {
+		return
+	}
+	for key, value := range domainCfg.Headers {
+		headers.Set(key, value)
+	}
+	for key, value := range domainCfg.Cookies {
+		headers.Set("Cookie", fmt.Sprintf("%s=%s", key, value))
+	}
+}
+
+// pageDirectives returns the robots directives of a page from its meta tags
+// and X-Robots-Tag headers, or no directives if robots are not respected
+func (s *Scraper) pageDirectives(e *colly.HTMLElement) robots.Directives {
+	if s.robots == nil {
+		return robots.Directives{}
+	}
+
+	var values []string
+	if e.Response.Headers != nil {
+		values = append(values, e.Response.Headers.Values("X-Robots-Tag")...)
+	}
+	e.ForEach("meta[name][content]", func(_ int, meta *colly.HTMLElement) {
+		name := strings.ToLower(meta.Attr("name"))
+		if name == "robots" || name == strings.ToLower(s.config.UserAgent) {
+			values = append(values, meta.Attr("content"))
+		}
+	})
+
+	return robots.ParseDirectives(values...)
+}
+
+// followLinks queues every link on the page that has not been visited yet
+// under the rules of the page's seed and returns the absolute URLs of all
+// followable links
+func (s *Scraper) followLinks(e *colly.HTMLElement, sd *seed) []string {
+	var links []string
+	depth := s.depth(e.Request) + 1
+	parent := s.requestedURL(e.Request)
+	e.ForEach("a[href]", func(_ int, a *colly.HTMLElement) {
+		link := a.Attr("href")
+		if s.robots != nil && robots.HasNoFollow(a.Attr("rel")) {
+			if s.config.Debug {
+				log.Printf("Skipping nofollow link: %s", link)
+			}
+			return
+		}
+		if s.config.Debug {
+			log.Printf("Found link: %s", link)
+		}
+		absURL := e.Request.AbsoluteURL(link)
+		if absURL == "" {
+			return
+		}
+		links = append(links, absURL)
+		if !s.visited(absURL) {
+			s.visit(e.Request, absURL, depth, parent, sd)
+		}
+	})
+	return links
+}
+
+// expandStoredLinks queues the links recorded for a skipped page so the
+// crawl still reaches pages below it
+func (s *Scraper) expandStoredLinks(r *colly.Request) {
+	sd := s.seedOf(s.requestedURL(r))
+	depth := s.depth(r) + 1
+	if depth > sd.depth {
+		return
+	}
+	record, ok := sd.storage.GetRecord(s.requestedURL(r))
+	if !ok {
+		return
+	}
+	for _, link := range record.Links {
+		if !s.visited(link) {
+			s.visit(r, link, depth, record.URL, sd)
+		}
+	}
+}
+
+// visited reports whether a URL has been visited under any of its spellings
+func (s *Scraper) visited(u string) bool {
+	return s.storage.IsVisited(s.urls.Key(u))
+}
+
+// markVisited marks a URL and its other spellings as visited
+func (s *Scraper) markVisited(u string) {
+	s.storage.MarkVisited(s.urls.Key(u))
+}
+
+// requestedURL returns the URL a request was made for, before any redirects
+func (s *Scraper) requestedURL(r *colly.Request) string {
+	if u, ok := s.requested.Load(r.ID); ok {
+		return u.(string)
+	}
+	return r.URL.String()
+}
+
+// savePage converts a page to markdown, saves it to the output of its seed
+// and stores its record
+func (s *Scraper) savePage(e *colly.HTMLElement, record storage.Record, sd *seed) {
+	// Extract with the seed's extraction profile or the rules of the domain
+	profile := e.Request.URL.Host
+	if sd.profile != "" {
+		profile = sd.profile
+	}
+
+	// Extract title
+	title := s.extractor.ExtractTitle(
+		profile,
+		e.DOM,
+		e.Request.URL.Path,
+	)
+
+	// Extract content
+	content := s.extractor.ExtractContent(profile, e.Request.URL, e.DOM)
+	contentHash := storage.HashContent(content)
+	metadata := s.extractor.ExtractMetadata(e.Request.URL, e.DOM)
+
+	// Save the canonical URL of a page declaring one instead of the page
+	if canonical := s.canonicalURL(record, metadata.Canonical, sd); canonical != "" {
+		s.saveDuplicate(record, title, contentHash, canonical, "canonical", sd.storage)
+		if err := s.visit(e.Request, canonical, record.Depth, record.URL, sd); err != nil && s.config.Debug {
+			log.Printf("Error visiting %s: %v", canonical, err)
+		}
+		return
+	}
+
+	// Record copies of pages saved under another URL instead of saving them again
+	if duplicate := sd.storage.FindDuplicate(record.URL, content); duplicate != nil {
+		reason := "near-duplicate"
+		if duplicate.Exact {
+			reason = "duplicate"
+		}
+		s.saveDuplicate(record, title, contentHash, duplicate.URL, reason, sd.storage)
+		return
+	}
+
+	// Find where the page will be saved so its images can be stored next to it
+	outputPath, err := sd.storage.OutputPath(record.URL, title)
+	if err != nil {
+		log.Printf("Error saving content for %s: %v", e.Request.URL, err)
+		sd.storage.ForgetContent(record.URL)
+		return
+	}
+	if s.images != nil {
+		content = s.localImages(content, outputPath, sd.storage)
+	}
+
+	// Generate the document with the configured header
+	doc, err := s.documents.Render(document.Page{
+		URL:         e.Request.URL.String(),
+		Canonical:   metadata.Canonical,
+		Title:       title,
+		Description: metadata.Description,
+		Language:    metadata.Language,
+		Author:      metadata.Author,
+		Keywords:    metadata.Keywords,
+		Published:   metadata.Published,
+		Modified:    metadata.Modified,
+		Image:       metadata.Image,
+		ScrapedAt:   time.Now(),
+		ContentHash: contentHash,
+		Depth:       record.Depth,
+		Metadata:    metadata,
+	}, content)
+	if err != nil {
+		log.Printf("Error rendering %s: %v", e.Request.URL, err)
+		sd.storage.ForgetContent(record.URL)
+		return
+	}
+
+	// Save chunks and content
+	if s.chunker != nil {
+		chunks := s.chunker.Split(e.Request.URL.String(), title, content)
+		record.ChunksPath, err = sd.storage.SaveChunks(record.URL, title, chunks)
+		if err != nil {
+			log.Printf("Error saving chunks for %s: %v", e.Request.URL, err)
+			sd.storage.ForgetContent(record.URL)
+			return
+		}
+		outputPath = record.ChunksPath
+	}
+	if s.chunker == nil || s.config.Chunks.Markdown {
+		outputPath, err = sd.storage.SaveContent(record.URL, title, doc)
+		if err != nil {
+			log.Printf("Error saving content for %s: %v", e.Request.URL, err)
+			sd.storage.ForgetContent(record.URL)
+			return
+		}
+	}
+
+	record.Title = title
+	record.OutputPath = outputPath
+	record.ContentHash = contentHash
+	record.Metadata = &metadata
+	if err := sd.storage.PutRecord(record); err != nil {
+		log.Printf("Error storing record for %s: %v", e.Request.URL, err)
+	}
+
+	s.stats.IncrementScraped()
+
+	if s.config.Debug {
+		log.Printf("Scraped %s -> %s", e.Request.URL, outputPath)
+	}
+}
+
+// canonicalURL returns the normalized canonical URL declared by a page when
+// it should be saved instead of the page: canonical URLs are respected, the
+// URL is another page within the crawl of the page's seed, and that page
+// does not declare this one as its canonical in turn
+func (s *Scraper) canonicalURL(record storage.Record, canonical string, sd *seed) string {
+	if !s.config.RespectCanonical || canonical == "" {
+		return ""
+	}
+	normalized, err := s.urls.Normalize(canonical)
+	if err != nil {
+		return ""
+	}
+	key := s.urls.Key(normalized)
+	if key == s.urls.Key(record.URL) || key == s.urls.Key(record.FinalURL) {
+		return ""
+	}
+	if !sd.allows(normalized) {
+		return ""
+	}
+	if other, ok := sd.storage.GetRecord(normalized); ok && s.urls.Key(other.DuplicateOf) == s.urls.Key(record.URL) {
+		return ""
+	}
+	return normalized
+}
+
+// saveDuplicate stores the record of a page that is saved under another URL,
+// removing any files saved for it by a previous run
+func (s *Scraper) saveDuplicate(record storage.Record, title, contentHash, original, reason string, output *storage.Storage) {
+	if err := output.RemoveContent(record.URL); err != nil {
+		log.Printf("Error removing duplicate %s: %v", record.URL, err)
+	}
+
+	record.Title = title
+	record.OutputPath = ""
+	record.ChunksPath = ""
+	record.ContentHash = contentHash
+	record.DuplicateOf = original
+	if err := output.PutRecord(record); err != nil {
+		log.Printf("Error storing record for %s: %v", record.URL, err)
+	}
+
+	if reason == "canonical" {
+		s.stats.IncrementCanonical()
+		if s.config.Debug {
+			log.Printf("Not saving %s (canonical URL is %s)", record.URL, original)
+		}
+		return
+	}
+
+	s.stats.IncrementDuplicates()
+	if s.config.Debug {
+		log.Printf("Not saving %s (%s of %s)", record.URL, reason, original)
+	}
+}
+
 // convertContentPatterns converts config content patterns to extractor patterns
 func convertContentPatterns(configPatterns map[string]config.ContentExtraction) map[string]content.ExtractionPatterns {
 	patterns := make(map[string]content.ExtractionPatterns)
@@ -216,6 +833,11 @@ func convertContentPatterns(configPatterns map[string]config.ContentExtraction)
 			TitlePattern:    p.TitlePattern,
 			ContentPatterns: p.ContentPatterns,
 			ExcludePatterns: p.ExcludePatterns,
+			Strategy:        content.Strategy(p.Strategy),
+
+			TitleSelector:    p.TitleSelector,
+			ContentSelectors: p.ContentSelectors,
+			ExcludeSelectors: p.ExcludeSelectors,
 		}
 	}
 	return patterns
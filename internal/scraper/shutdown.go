@@ -0,0 +1,59 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ErrStopped is returned by Start when the crawl was stopped before it completed
+var ErrStopped = errors.New("scraping stopped early")
+
+// cancelTransport ties every outgoing request to a context so in-flight
+// requests can be aborted when a shutdown does not drain in time
+type cancelTransport struct {
+	base http.RoundTripper
+	ctx  context.Context
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *cancelTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
+
+// stopping reports whether the crawl has been asked to stop, by a signal
+// or by reaching one of its limits
+func (s *Scraper) stopping() bool {
+	return s.ctx.Err() != nil || s.budget.draining.Load()
+}
+
+// wait blocks until the collector has finished. Once ctx is cancelled no new
+// requests are started and in-flight requests get the shutdown timeout to
+// finish before they are aborted.
+func (s *Scraper) wait(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		s.collector.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	log.Printf("Stopping: waiting up to %s for in-flight requests", s.config.ShutdownTimeout)
+	timer := time.NewTimer(s.config.ShutdownTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		log.Printf("Aborting in-flight requests")
+		s.abortRequests()
+		<-done
+	}
+}
@@ -2,23 +2,48 @@ package stats
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
 // Stats tracks scraping statistics
 type Stats struct {
-	URLsScanned int
-	URLsScraped int
-	URLsSkipped int
-	StartTime   time.Time
-	mutex       sync.Mutex
+	URLsScanned   int
+	URLsScraped   int
+	URLsSkipped   int
+	URLsUnchanged int
+	URLsBlocked   int
+	Duplicates    int
+	Canonical     int
+	TooLarge      int
+	PagesNoIndex  int
+	Retries       int
+	URLsFailed    int
+	Throttled     int
+	Images        int
+	LinksLocal    int
+	LinksMissing  int
+	Bytes         int64
+	StopReason    string
+	LimitReason   string
+	Domains       map[string]*DomainStats
+	StartTime     time.Time
+	mutex         sync.Mutex
+}
+
+// DomainStats tracks requests made under a domain-specific rate limit
+type DomainStats struct {
+	Limits   string
+	Requests int
 }
 
 // New creates a new Stats tracker
 func New() *Stats {
 	return &Stats{
 		StartTime: time.Now(),
+		Domains:   make(map[string]*DomainStats),
 	}
 }
 
@@ -43,16 +68,182 @@ func (s *Stats) IncrementSkipped() {
 	s.mutex.Unlock()
 }
 
+// IncrementUnchanged increments the number of URLs revalidated as unchanged
+func (s *Stats) IncrementUnchanged() {
+	s.mutex.Lock()
+	s.URLsUnchanged++
+	s.mutex.Unlock()
+}
+
+// IncrementBlocked increments the number of URLs blocked by robots.txt
+func (s *Stats) IncrementBlocked() {
+	s.mutex.Lock()
+	s.URLsBlocked++
+	s.mutex.Unlock()
+}
+
+// IncrementNoIndex increments the number of pages not saved due to noindex
+func (s *Stats) IncrementNoIndex() {
+	s.mutex.Lock()
+	s.PagesNoIndex++
+	s.mutex.Unlock()
+}
+
+// IncrementRetried increments the number of retried requests
+func (s *Stats) IncrementRetried() {
+	s.mutex.Lock()
+	s.Retries++
+	s.mutex.Unlock()
+}
+
+// IncrementFailed increments the number of URLs that could not be scraped
+func (s *Stats) IncrementFailed() {
+	s.mutex.Lock()
+	s.URLsFailed++
+	s.mutex.Unlock()
+}
+
+// IncrementThrottled increments the number of times a host was slowed down
+func (s *Stats) IncrementThrottled() {
+	s.mutex.Lock()
+	s.Throttled++
+	s.mutex.Unlock()
+}
+
+// IncrementDuplicates increments the number of pages not saved because
+// another URL had the same content
+func (s *Stats) IncrementDuplicates() {
+	s.mutex.Lock()
+	s.Duplicates++
+	s.mutex.Unlock()
+}
+
+// IncrementCanonical increments the number of pages not saved because they
+// declare another URL as canonical
+func (s *Stats) IncrementCanonical() {
+	s.mutex.Lock()
+	s.Canonical++
+	s.mutex.Unlock()
+}
+
+// IncrementTooLarge increments the number of pages not saved because they
+// exceed the page size limit
+func (s *Stats) IncrementTooLarge() {
+	s.mutex.Lock()
+	s.TooLarge++
+	s.mutex.Unlock()
+}
+
+// AddBytes records downloaded bytes and returns the total downloaded so far
+func (s *Stats) AddBytes(n int64) int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Bytes += n
+	return s.Bytes
+}
+
+// IncrementImages increments the number of images downloaded
+func (s *Stats) IncrementImages() {
+	s.mutex.Lock()
+	s.Images++
+	s.mutex.Unlock()
+}
+
+// AddLinks records the links rewritten to local files and the number of
+// linked pages that were never captured
+func (s *Stats) AddLinks(rewritten, missing int) {
+	s.mutex.Lock()
+	s.LinksLocal += rewritten
+	s.LinksMissing += missing
+	s.mutex.Unlock()
+}
+
+// AddDomain registers a domain pattern with its own rate limits
+func (s *Stats) AddDomain(pattern, limits string) {
+	s.mutex.Lock()
+	s.Domains[pattern] = &DomainStats{Limits: limits}
+	s.mutex.Unlock()
+}
+
+// IncrementDomain increments the number of requests made under a domain pattern
+func (s *Stats) IncrementDomain(pattern string) {
+	s.mutex.Lock()
+	if domain, ok := s.Domains[pattern]; ok {
+		domain.Requests++
+	}
+	s.mutex.Unlock()
+}
+
+// Stop records that the crawl stopped early and why
+func (s *Stats) Stop(reason string) {
+	s.mutex.Lock()
+	s.StopReason = reason
+	s.mutex.Unlock()
+}
+
+// Limit records that the crawl reached one of its limits, keeping the
+// first limit reached
+func (s *Stats) Limit(reason string) {
+	s.mutex.Lock()
+	if s.LimitReason == "" {
+		s.LimitReason = reason
+	}
+	s.mutex.Unlock()
+}
+
 // GetSummary returns a formatted summary of the statistics
 func (s *Stats) GetSummary() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	duration := time.Since(s.StartTime)
+	status := "completed"
+	if s.StopReason != "" {
+		status = fmt.Sprintf("stopped early (%s)", s.StopReason)
+	} else if s.LimitReason != "" {
+		status = fmt.Sprintf("limit reached (%s)", s.LimitReason)
+	}
 	return fmt.Sprintf(`
 Scraping Statistics:
+Status: %s
 URLs Scanned: %d
 URLs Scraped: %d
 URLs Skipped: %d
-Total Time: %s
-`, s.URLsScanned, s.URLsScraped, s.URLsSkipped, duration.Round(time.Second))
+URLs Unchanged (304): %d
+URLs Blocked (robots.txt): %d
+Pages Skipped (noindex): %d
+Pages Skipped (duplicate): %d
+Pages Skipped (canonical): %d
+Pages Skipped (too large): %d
+URLs Failed: %d
+Retries: %d
+Slowdowns (429/503): %d
+Images Downloaded: %d
+Links Made Local: %d
+Linked Pages Not Captured: %d
+Bytes Downloaded: %d
+%sTotal Time: %s
+`, status, s.URLsScanned, s.URLsScraped, s.URLsSkipped, s.URLsUnchanged, s.URLsBlocked, s.PagesNoIndex, s.Duplicates, s.Canonical, s.TooLarge, s.URLsFailed, s.Retries, s.Throttled, s.Images, s.LinksLocal, s.LinksMissing, s.Bytes, s.domainSummary(), duration.Round(time.Second))
+}
+
+// domainSummary lists the domain-specific rate limits and their request counts
+func (s *Stats) domainSummary() string {
+	if len(s.Domains) == 0 {
+		return ""
+	}
+
+	patterns := make([]string, 0, len(s.Domains))
+	for pattern := range s.Domains {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	var summary strings.Builder
+	summary.WriteString("Domain Limits:\n")
+	for _, pattern := range patterns {
+		domain := s.Domains[pattern]
+		summary.WriteString(fmt.Sprintf("  %s (%s): %d requests\n", pattern, domain.Limits, domain.Requests))
+	}
+	return summary.String()
 }
 
 // GetStats returns the current statistics
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// assetsDir is the directory next to the markdown files holding their
+// downloaded assets
+const assetsDir = "assets"
+
+// AssetDir returns the directory holding the assets of the page saved at outputPath
+func (s *Storage) AssetDir(outputPath string) string {
+	return filepath.Join(filepath.Dir(outputPath), assetsDir)
+}
+
+// SaveAsset stores a downloaded file in the assets directory of the page
+// saved at outputPath and returns the path written. Files are named after
+// a hash of their content, so identical files are stored only once.
+func (s *Storage) SaveAsset(outputPath string, data []byte, ext string) (string, error) {
+	hash := sha256.Sum256(data)
+	assetPath := filepath.Join(s.AssetDir(outputPath), hex.EncodeToString(hash[:])[:16]+ext)
+	if _, err := os.Stat(assetPath); err == nil {
+		return assetPath, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(assetPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create assets directory: %w", err)
+	}
+	if err := writeFileAtomic(assetPath, data); err != nil {
+		return "", fmt.Errorf("failed to write asset: %w", err)
+	}
+	return assetPath, nil
+}
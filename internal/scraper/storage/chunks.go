@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ncecere/bullnose/internal/scraper/chunker"
+)
+
+// SaveChunks writes the chunks of a page as JSON Lines next to where its
+// markdown is saved, with a .jsonl extension, and returns the path written
+func (s *Storage) SaveChunks(pageURL, title string, chunks []chunker.Chunk) (string, error) {
+	outputPath, err := s.OutputPath(pageURL, title)
+	if err != nil {
+		return "", err
+	}
+	chunksPath := strings.TrimSuffix(outputPath, ".md") + ".jsonl"
+
+	// Remove the file written by a previous run if the page has moved
+	if record, ok := s.GetRecord(pageURL); ok && record.ChunksPath != "" &&
+		claimKey(record.ChunksPath) != claimKey(chunksPath) {
+		s.releasePath(pageURL, record.ChunksPath)
+		if err := os.Remove(record.ChunksPath); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to remove previous chunks: %w", err)
+		}
+	}
+
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	for _, chunk := range chunks {
+		if err := encoder.Encode(chunk); err != nil {
+			return "", fmt.Errorf("failed to encode chunk: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(chunksPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := writeFileAtomic(chunksPath, data.Bytes()); err != nil {
+		return "", fmt.Errorf("failed to write chunks: %w", err)
+	}
+	return chunksPath, nil
+}
@@ -0,0 +1,283 @@
+package storage
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// shingleSize is the number of consecutive words hashed together for SimHash
+const shingleSize = 3
+
+// minShingles is the number of shingles a page needs before it is compared
+// for near-duplicates, as the fingerprints of short pages say little
+const minShingles = 20
+
+// fingerprint identifies the content of a saved page
+type fingerprint struct {
+	hash    string // SHA-256 of the content
+	simhash uint64
+	near    bool // whether the simhash has enough shingles to compare
+}
+
+// contentIndex holds the fingerprints of saved pages, indexed by content hash
+// and by bands of their SimHash so a page is only compared with the pages
+// that can be its duplicates
+type contentIndex struct {
+	fingerprints map[string]fingerprint       // URL -> fingerprint
+	hashes       map[string]map[string]bool   // content hash -> URLs
+	bands        []map[uint64]map[string]bool // band -> bits of the band -> URLs
+}
+
+// newContentIndex creates an empty content index
+func newContentIndex() *contentIndex {
+	return &contentIndex{
+		fingerprints: make(map[string]fingerprint),
+		hashes:       make(map[string]map[string]bool),
+	}
+}
+
+// setBands splits SimHashes into n bands and reindexes the fingerprints.
+// SimHashes differing in fewer than n bits are equal in at least one band.
+func (c *contentIndex) setBands(n int) {
+	c.bands = make([]map[uint64]map[string]bool, n)
+	for i := range c.bands {
+		c.bands[i] = make(map[uint64]map[string]bool)
+	}
+	for u, fp := range c.fingerprints {
+		c.indexBands(u, fp, true)
+	}
+}
+
+// add registers the fingerprint of a page, replacing an earlier one
+func (c *contentIndex) add(u string, fp fingerprint) {
+	c.remove(u)
+	c.fingerprints[u] = fp
+	if c.hashes[fp.hash] == nil {
+		c.hashes[fp.hash] = make(map[string]bool)
+	}
+	c.hashes[fp.hash][u] = true
+	c.indexBands(u, fp, true)
+}
+
+// remove drops the fingerprint of a page
+func (c *contentIndex) remove(u string) {
+	fp, ok := c.fingerprints[u]
+	if !ok {
+		return
+	}
+	delete(c.fingerprints, u)
+	delete(c.hashes[fp.hash], u)
+	if len(c.hashes[fp.hash]) == 0 {
+		delete(c.hashes, fp.hash)
+	}
+	c.indexBands(u, fp, false)
+}
+
+// indexBands adds a page to or removes it from the buckets of its SimHash
+func (c *contentIndex) indexBands(u string, fp fingerprint, add bool) {
+	if !fp.near {
+		return
+	}
+	for i, value := range simHashBands(fp.simhash, len(c.bands)) {
+		bucket := c.bands[i][value]
+		switch {
+		case add && bucket == nil:
+			c.bands[i][value] = map[string]bool{u: true}
+		case add:
+			bucket[u] = true
+		default:
+			delete(bucket, u)
+			if len(bucket) == 0 {
+				delete(c.bands[i], value)
+			}
+		}
+	}
+}
+
+// candidates returns the pages sharing a band of their SimHash with fp
+func (c *contentIndex) candidates(fp fingerprint) map[string]bool {
+	candidates := make(map[string]bool)
+	for i, value := range simHashBands(fp.simhash, len(c.bands)) {
+		for u := range c.bands[i][value] {
+			candidates[u] = true
+		}
+	}
+	return candidates
+}
+
+// simHashBands splits a SimHash into n bands of consecutive bits
+func simHashBands(simhash uint64, n int) []uint64 {
+	bands := make([]uint64, n)
+	for i := range bands {
+		start, end := i*64/n, (i+1)*64/n
+		mask := uint64(1)<<(end-start) - 1
+		if end-start == 64 {
+			mask = ^uint64(0)
+		}
+		bands[i] = (simhash >> start) & mask
+	}
+	return bands
+}
+
+// Duplicate describes the earlier page whose content a page repeats
+type Duplicate struct {
+	URL   string
+	Exact bool
+}
+
+// EnableDedup turns on content deduplication. With near set, pages whose
+// SimHash fingerprints differ in at most maxDistance of their 64 bits are
+// also treated as duplicates.
+func (s *Storage) EnableDedup(near bool, maxDistance int) {
+	s.dedup = true
+	s.nearDuplicates = near
+	s.maxDistance = maxDistance
+
+	// Near-duplicates differ in at most maxDistance bits, so they share at
+	// least one of maxDistance+1 bands
+	s.recordsMutex.Lock()
+	s.contents.setBands(min(maxDistance+1, 64))
+	s.recordsMutex.Unlock()
+}
+
+// FindDuplicate returns the saved page whose content the content of a page
+// duplicates. When there is none the content is registered for the page, so
+// later copies are found even before the page has been written.
+func (s *Storage) FindDuplicate(pageURL, content string) *Duplicate {
+	if !s.dedup {
+		return nil
+	}
+
+	fp := newFingerprint(content)
+
+	s.recordsMutex.Lock()
+	defer s.recordsMutex.Unlock()
+
+	var duplicate *Duplicate
+	for owner := range s.contents.hashes[fp.hash] {
+		// Prefer the smallest URL so reruns pick the same original
+		if owner != pageURL && (duplicate == nil || owner < duplicate.URL) {
+			duplicate = &Duplicate{URL: owner, Exact: true}
+		}
+	}
+
+	if duplicate == nil && s.nearDuplicates && fp.near {
+		bestDistance := s.maxDistance + 1
+		for owner := range s.contents.candidates(fp) {
+			if owner == pageURL {
+				continue
+			}
+			distance := bits.OnesCount64(fp.simhash ^ s.contents.fingerprints[owner].simhash)
+			if distance < bestDistance || (distance == bestDistance && duplicate != nil && owner < duplicate.URL) {
+				duplicate = &Duplicate{URL: owner}
+				bestDistance = distance
+			}
+		}
+	}
+
+	if duplicate != nil {
+		// Pages recorded as copies of this page now lead to its original
+		s.contents.remove(pageURL)
+		for _, record := range s.records {
+			if record.DuplicateOf == pageURL {
+				record.DuplicateOf = duplicate.URL
+			}
+		}
+		return duplicate
+	}
+	s.contents.add(pageURL, fp)
+	return nil
+}
+
+// ForgetContent drops the content registered for a page that could not be saved
+func (s *Storage) ForgetContent(pageURL string) {
+	s.recordsMutex.Lock()
+	s.contents.remove(pageURL)
+	s.recordsMutex.Unlock()
+}
+
+// RemoveContent deletes the files a previous run saved for a page that is
+// now a duplicate
+func (s *Storage) RemoveContent(pageURL string) error {
+	record, ok := s.GetRecord(pageURL)
+	if !ok {
+		return nil
+	}
+	for _, path := range []string{record.OutputPath, record.ChunksPath} {
+		if path == "" {
+			continue
+		}
+		s.releasePath(pageURL, path)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove previous file: %w", err)
+		}
+	}
+	return nil
+}
+
+// newFingerprint computes the exact hash and the SimHash of content
+func newFingerprint(content string) fingerprint {
+	simhash, shingles := simHash(content)
+	return fingerprint{
+		hash:    HashContent(content),
+		simhash: simhash,
+		near:    shingles >= minShingles,
+	}
+}
+
+// simHash computes the 64-bit SimHash of the word shingles of a text and
+// returns it with the number of shingles. Only letters and digits count as
+// words, so markdown syntax does not affect the fingerprint.
+func simHash(text string) (uint64, int) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var weights [64]int
+	shingles := 0
+	for i := 0; i+shingleSize <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+shingleSize], " ")))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+		shingles++
+	}
+
+	var simhash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			simhash |= 1 << bit
+		}
+	}
+	return simhash, shingles
+}
+
+// formatSimHash encodes a fingerprint's SimHash for the records, leaving it
+// out when the page is too short to compare
+func formatSimHash(fp fingerprint) string {
+	if !fp.near {
+		return ""
+	}
+	return fmt.Sprintf("%016x", fp.simhash)
+}
+
+// parseSimHash restores the fingerprint of a record saved by a previous run
+func parseSimHash(record *Record) fingerprint {
+	fp := fingerprint{hash: record.ContentHash}
+	if simhash, err := strconv.ParseUint(record.SimHash, 16, 64); err == nil && record.SimHash != "" {
+		fp.simhash = simhash
+		fp.near = true
+	}
+	return fp
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// TestContentIndexCandidates checks that the SimHash bands find every page
+// within the maximum distance, as a comparison with every page would
+func TestContentIndexCandidates(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, maxDistance := range []int{0, 3, 10, 64} {
+		index := newContentIndex()
+		index.setBands(min(maxDistance+1, 64))
+
+		base := rng.Uint64()
+		for i := 0; i < 500; i++ {
+			simhash := base
+			for flips := rng.Intn(12); flips > 0; flips-- {
+				simhash ^= 1 << rng.Intn(64)
+			}
+			index.add(fmt.Sprintf("https://example.com/%d", i), fingerprint{hash: fmt.Sprint(i), simhash: simhash, near: true})
+		}
+
+		fp := fingerprint{simhash: base, near: true}
+		candidates := index.candidates(fp)
+		for u, other := range index.fingerprints {
+			if bits.OnesCount64(fp.simhash^other.simhash) <= maxDistance && !candidates[u] {
+				t.Errorf("max distance %d: %s is not a candidate", maxDistance, u)
+			}
+		}
+	}
+}
+
+func TestSimHashBands(t *testing.T) {
+	const simhash = 0x0123456789abcdef
+
+	tests := []struct {
+		n    int
+		want []uint64
+	}{
+		{n: 1, want: []uint64{simhash}},
+		{n: 2, want: []uint64{0x89abcdef, 0x01234567}},
+		{n: 3, want: []uint64{0x0bcdef, 0x0b3c4d, 0x0048d1}},
+		{n: 4, want: []uint64{0xcdef, 0x89ab, 0x4567, 0x0123}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.n), func(t *testing.T) {
+			got := simHashBands(simhash, tt.n)
+			if fmt.Sprintf("%x", got) != fmt.Sprintf("%x", tt.want) {
+				t.Errorf("simHashBands(%d) = %x, want %x", tt.n, got, tt.want)
+			}
+		})
+	}
+
+	// Every bit lands in exactly one band
+	for n := 1; n <= 64; n++ {
+		total := 0
+		for _, band := range simHashBands(^uint64(0), n) {
+			total += bits.OnesCount64(band)
+		}
+		if total != 64 {
+			t.Errorf("simHashBands(%d) covers %d bits, want 64", n, total)
+		}
+	}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	var words []string
+	for i := 0; i < 200; i++ {
+		words = append(words, fmt.Sprintf("word%d", i))
+	}
+	original := strings.Join(words, " ")
+	words[100] = "changed"
+	edited := strings.Join(words, " ")
+
+	tests := []struct {
+		name    string
+		near    bool
+		saved   string
+		content string
+		want    *Duplicate
+	}{
+		{name: "exact copy", saved: original, content: original, want: &Duplicate{URL: "https://example.com/a", Exact: true}},
+		{name: "different page", near: true, saved: original, content: "something else entirely " + strings.Repeat("other words ", 50)},
+		{name: "near copy", near: true, saved: original, content: edited, want: &Duplicate{URL: "https://example.com/a"}},
+		{name: "near copy without near-duplicates", saved: original, content: edited},
+		{name: "short pages", near: true, saved: "one two three", content: "one two four"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(t.TempDir(), LayoutTitle, 0, true)
+			s.EnableDedup(tt.near, 10)
+			if duplicate := s.FindDuplicate("https://example.com/a", tt.saved); duplicate != nil {
+				t.Fatalf("FindDuplicate() = %+v for the first page", duplicate)
+			}
+
+			got := s.FindDuplicate("https://example.com/b", tt.content)
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil || *got != *tt.want:
+				t.Errorf("FindDuplicate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
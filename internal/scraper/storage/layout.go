@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Layout determines how output files are named
+type Layout string
+
+const (
+	// LayoutTitle names files after the sanitized page title
+	LayoutTitle Layout = "title"
+	// LayoutURLPath mirrors the URL path, e.g. /docs/guide/install -> docs/guide/install.md
+	LayoutURLPath Layout = "url-path"
+	// LayoutHash names files after a hash of the URL
+	LayoutHash Layout = "hash"
+)
+
+// Layouts lists all supported output layouts
+var Layouts = []Layout{LayoutTitle, LayoutURLPath, LayoutHash}
+
+// pageExtensions are URL path extensions dropped from url-path file names
+var pageExtensions = map[string]bool{
+	".html":  true,
+	".htm":   true,
+	".xhtml": true,
+	".php":   true,
+	".asp":   true,
+	".aspx":  true,
+	".jsp":   true,
+}
+
+// maxNameLength caps the length of a single path segment in runes
+const maxNameLength = 100
+
+// ParseLayout validates a layout name
+func ParseLayout(name string) (Layout, error) {
+	for _, layout := range Layouts {
+		if string(layout) == name {
+			return layout, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output layout %q", name)
+}
+
+// outputPath returns the path a page should be written to, relative to the
+// output directory and without the .md extension
+func (s *Storage) outputPath(u *url.URL, title string) string {
+	var name string
+	switch s.layout {
+	case LayoutURLPath:
+		name = s.urlPathName(u)
+	case LayoutHash:
+		name = s.hashURL(u.String())
+	default:
+		name = s.sanitizeFilename(title)
+	}
+	if name == "" {
+		name = s.hashURL(u.String())
+	}
+	return filepath.Join(u.Host, name)
+}
+
+// urlPathName builds a relative file name mirroring the URL path
+func (s *Storage) urlPathName(u *url.URL) string {
+	urlPath := u.Path
+	if urlPath == "" || strings.HasSuffix(urlPath, "/") {
+		urlPath += "index"
+	}
+	if ext := path.Ext(urlPath); pageExtensions[strings.ToLower(ext)] {
+		urlPath = strings.TrimSuffix(urlPath, ext)
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(urlPath, "/") {
+		if segment = s.sanitizeFilename(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		segments = []string{"index"}
+	}
+
+	// Distinguish pages that only differ by query string
+	if u.RawQuery != "" {
+		segments[len(segments)-1] += "-" + s.hashURL(u.RawQuery)[:8]
+	}
+
+	return filepath.Join(segments...)
+}
+
+// claimPath reserves an output path for a URL. When the path is already
+// owned by a different URL a suffix derived from the URL is appended. Which
+// of the colliding pages keeps the plain name is settled by SettlePaths.
+func (s *Storage) claimPath(pageURL, name string) string {
+	s.recordsMutex.Lock()
+	defer s.recordsMutex.Unlock()
+
+	candidate := filepath.Join(s.outputDir, name+".md")
+	if owner, ok := s.claims[claimKey(candidate)]; ok && owner != pageURL {
+		candidate = s.suffixedPath(candidate, pageURL)
+	}
+
+	s.claims[claimKey(candidate)] = pageURL
+	return candidate
+}
+
+// suffixedPath returns the path a page whose plain path is taken is saved
+// to. The suffix is a short hash of the URL, or the full hash in the unlikely
+// case that the short one is taken too.
+func (s *Storage) suffixedPath(plainPath, pageURL string) string {
+	ext := filepath.Ext(plainPath)
+	base := strings.TrimSuffix(plainPath, ext)
+	hash := s.hashURL(pageURL)
+	candidate := base + "-" + hash[:8] + ext
+	if owner, ok := s.claims[claimKey(candidate)]; ok && owner != pageURL {
+		candidate = base + "-" + hash + ext
+	}
+	return candidate
+}
+
+// plainPath returns the path a page saved at outputPath has without the
+// suffix added on a collision
+func (s *Storage) plainPath(outputPath, pageURL string) string {
+	ext := filepath.Ext(outputPath)
+	base := strings.TrimSuffix(outputPath, ext)
+	hash := s.hashURL(pageURL)
+	for _, suffix := range []string{"-" + hash[:8], "-" + hash} {
+		if strings.HasSuffix(base, suffix) {
+			return strings.TrimSuffix(base, suffix) + ext
+		}
+	}
+	return outputPath
+}
+
+// SettlePaths makes the paths of pages whose names collide independent of
+// the order they were saved in: the smallest URL gets the plain name and
+// the others keep a suffix derived from their URL. Files are moved to match.
+func (s *Storage) SettlePaths() error {
+	s.recordsMutex.Lock()
+	defer s.recordsMutex.Unlock()
+
+	groups := make(map[string][]*Record)
+	for _, record := range s.records {
+		if record.OutputPath == "" || record.DuplicateOf != "" {
+			continue
+		}
+		key := claimKey(s.plainPath(record.OutputPath, record.URL))
+		groups[key] = append(groups[key], record)
+	}
+
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].URL < group[j].URL
+		})
+		first := group[0]
+		plain := s.plainPath(first.OutputPath, first.URL)
+
+		// Move the other pages off the plain name before the first takes it
+		for _, record := range group[1:] {
+			if claimKey(record.OutputPath) == claimKey(plain) {
+				if err := s.movePage(record, s.suffixedPath(plain, record.URL)); err != nil {
+					return err
+				}
+			}
+		}
+		if claimKey(first.OutputPath) != claimKey(plain) {
+			if err := s.movePage(first, plain); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// movePage moves the files of a page to a new output path, updating its
+// record and claims
+func (s *Storage) movePage(record *Record, outputPath string) error {
+	ext := filepath.Ext(record.OutputPath)
+	outputPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ext
+	chunksPath := ""
+	if record.ChunksPath != "" {
+		chunksPath = strings.TrimSuffix(outputPath, ext) + filepath.Ext(record.ChunksPath)
+	}
+
+	moves := [][2]string{{record.OutputPath, outputPath}}
+	if chunksPath != "" && record.ChunksPath != record.OutputPath {
+		moves = append(moves, [2]string{record.ChunksPath, chunksPath})
+	}
+	for _, move := range moves {
+		if err := os.Rename(move[0], move[1]); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to move %s: %w", move[0], err)
+		}
+	}
+
+	if s.claims[claimKey(record.OutputPath)] == record.URL {
+		delete(s.claims, claimKey(record.OutputPath))
+	}
+	s.claims[claimKey(outputPath)] = record.URL
+	record.OutputPath = outputPath
+	if chunksPath != "" {
+		record.ChunksPath = chunksPath
+	}
+	return nil
+}
+
+// releasePath drops a URL's claim on a path it no longer uses
+func (s *Storage) releasePath(pageURL, outputPath string) {
+	s.recordsMutex.Lock()
+	defer s.recordsMutex.Unlock()
+	if s.claims[claimKey(outputPath)] == pageURL {
+		delete(s.claims, claimKey(outputPath))
+	}
+}
+
+// claimKey normalizes a path so claims also hold on case-insensitive file
+// systems. The extension is dropped so the markdown and the chunks of a
+// page share one claim.
+func claimKey(outputPath string) string {
+	return strings.ToLower(strings.TrimSuffix(outputPath, filepath.Ext(outputPath)))
+}
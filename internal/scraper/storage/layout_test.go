@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestSettlePathsOrder checks that pages with the same title end up at the
+// same paths whatever order they were saved in
+func TestSettlePathsOrder(t *testing.T) {
+	urls := []string{
+		"https://example.com/a/overview",
+		"https://example.com/b/overview",
+		"https://example.com/c/overview",
+	}
+	reversed := []string{urls[2], urls[1], urls[0]}
+
+	var results []map[string]string
+	for _, order := range [][]string{urls, reversed} {
+		dir := t.TempDir()
+		s := New(dir, LayoutTitle, 0, true)
+		for _, u := range order {
+			path, err := s.SaveContent(u, "Overview", u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.PutRecord(Record{URL: u, OutputPath: path}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.SettlePaths(); err != nil {
+			t.Fatal(err)
+		}
+
+		paths := make(map[string]string)
+		for _, u := range urls {
+			record, _ := s.GetRecord(u)
+			content, err := os.ReadFile(record.OutputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != u {
+				t.Errorf("%s holds the content of %s", record.OutputPath, content)
+			}
+			rel, _ := filepath.Rel(dir, record.OutputPath)
+			paths[u] = filepath.ToSlash(rel)
+		}
+		results = append(results, paths)
+	}
+
+	if got := results[0][urls[0]]; got != "example.com/overview.md" {
+		t.Errorf("smallest URL saved to %s, want example.com/overview.md", got)
+	}
+	if !reflect.DeepEqual(results[0], results[1]) {
+		t.Errorf("paths depend on the save order:\n%v\n%v", results[0], results[1])
+	}
+}
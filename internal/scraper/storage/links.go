@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// missingLinksFile is the name of the report of links to pages that were
+// never captured
+const missingLinksFile = "missing-links.json"
+
+// MissingLink is a link target that was not captured, with the pages linking to it
+type MissingLink struct {
+	URL        string   `json:"url"`
+	LinkedFrom []string `json:"linked_from"`
+}
+
+// Pages returns the records of all pages with a saved markdown file
+func (s *Storage) Pages() []Record {
+	s.recordsMutex.Lock()
+	defer s.recordsMutex.Unlock()
+	pages := make([]Record, 0, len(s.records))
+	for _, record := range s.records {
+		if filepath.Ext(record.OutputPath) == ".md" {
+			pages = append(pages, *record)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].URL < pages[j].URL
+	})
+	return pages
+}
+
+// Duplicates returns the URLs of pages that were not saved because they
+// duplicate another page, mapped to the URL of that page
+func (s *Storage) Duplicates() map[string]string {
+	s.recordsMutex.Lock()
+	defer s.recordsMutex.Unlock()
+	duplicates := make(map[string]string)
+	for _, record := range s.records {
+		if record.DuplicateOf != "" {
+			duplicates[record.URL] = record.DuplicateOf
+		}
+	}
+	return duplicates
+}
+
+// UpdateContent replaces the content of a saved output file
+func (s *Storage) UpdateContent(outputPath, content string) error {
+	if err := writeFileAtomic(outputPath, []byte(content)); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// SetLocalLinks records the links of a page that point at local files,
+// mapped to the URLs they replaced
+func (s *Storage) SetLocalLinks(pageURL string, links map[string]string) {
+	s.recordsMutex.Lock()
+	defer s.recordsMutex.Unlock()
+	if record, ok := s.records[pageURL]; ok {
+		if len(links) == 0 {
+			links = nil
+		}
+		record.LocalLinks = links
+	}
+}
+
+// RelativeLink returns the link to a file, such as an asset or another
+// page, relative to the page saved at outputPath
+func (s *Storage) RelativeLink(outputPath, targetPath string) string {
+	link, err := filepath.Rel(filepath.Dir(outputPath), targetPath)
+	if err != nil {
+		return targetPath
+	}
+	return filepath.ToSlash(link)
+}
+
+// WriteMissingLinks writes the report of links to pages that were never
+// captured, keyed by target URL, or removes a stale report when there are none
+func (s *Storage) WriteMissingLinks(missing map[string][]string) error {
+	path := filepath.Join(s.outputDir, missingLinksFile)
+	if len(missing) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove missing links report: %w", err)
+		}
+		return nil
+	}
+
+	links := make([]MissingLink, 0, len(missing))
+	for target, sources := range missing {
+		sort.Strings(sources)
+		links = append(links, MissingLink{URL: target, LinkedFrom: sources})
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].URL < links[j].URL
+	})
+
+	data, err := json.MarshalIndent(links, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode missing links report: %w", err)
+	}
+	if err := os.MkdirAll(s.outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write missing links report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ncecere/bullnose/internal/scraper/content"
+)
+
+const (
+	// manifestFile is the JSON Lines manifest in the output directory
+	manifestFile = "manifest.jsonl"
+	// manifestCSVFile is the optional CSV manifest in the output directory
+	manifestCSVFile = "manifest.csv"
+)
+
+// ManifestEntry describes one saved page in the crawl manifest
+type ManifestEntry struct {
+	URL         string    `json:"url"`
+	FinalURL    string    `json:"final_url"`
+	Title       string    `json:"title"`
+	OutputPath  string    `json:"output_path"`
+	ChunksPath  string    `json:"chunks_path,omitempty"`
+	DuplicateOf string    `json:"duplicate_of,omitempty"`
+	StatusCode  int       `json:"status_code"`
+	ContentHash string    `json:"content_hash"`
+	Depth       int       `json:"depth"`
+	ParentURL   string    `json:"parent_url,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+
+	Metadata *content.Metadata `json:"metadata,omitempty"`
+}
+
+// manifestCSVHeader lists the CSV columns in the order they are written
+var manifestCSVHeader = []string{
+	"url", "final_url", "title", "output_path", "status_code",
+	"content_hash", "depth", "parent_url", "fetched_at",
+	"canonical", "description", "language", "author", "published", "modified",
+	"chunks_path", "duplicate_of",
+}
+
+// EnableManifest turns on the manifest, optionally also written as CSV
+func (s *Storage) EnableManifest(writeCSV bool) {
+	s.manifest = true
+	s.manifestCSV = writeCSV
+}
+
+// WriteManifest rewrites the manifest with exactly one entry per saved or
+// duplicate page, covering pages skipped in this run as well
+func (s *Storage) WriteManifest() error {
+	if !s.manifest {
+		return nil
+	}
+
+	s.recordsMutex.Lock()
+	entries := make([]ManifestEntry, 0, len(s.records))
+	for _, record := range s.records {
+		if record.OutputPath != "" || record.DuplicateOf != "" {
+			entries = append(entries, s.manifestEntry(record))
+		}
+	}
+	s.recordsMutex.Unlock()
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].URL < entries[j].URL
+	})
+
+	var jsonl bytes.Buffer
+	encoder := json.NewEncoder(&jsonl)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("failed to encode manifest entry: %w", err)
+		}
+	}
+
+	s.manifestMutex.Lock()
+	defer s.manifestMutex.Unlock()
+
+	if err := os.MkdirAll(s.outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(s.outputDir, manifestFile), jsonl.Bytes()); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if !s.manifestCSV {
+		return nil
+	}
+
+	var data bytes.Buffer
+	writer := csv.NewWriter(&data)
+	if err := writer.Write(manifestCSVHeader); err != nil {
+		return fmt.Errorf("failed to encode CSV manifest: %w", err)
+	}
+	for _, entry := range entries {
+		var metadata content.Metadata
+		if entry.Metadata != nil {
+			metadata = *entry.Metadata
+		}
+		if err := writer.Write([]string{
+			entry.URL,
+			entry.FinalURL,
+			entry.Title,
+			entry.OutputPath,
+			strconv.Itoa(entry.StatusCode),
+			entry.ContentHash,
+			strconv.Itoa(entry.Depth),
+			entry.ParentURL,
+			entry.FetchedAt.Format(time.RFC3339),
+			metadata.Canonical,
+			metadata.Description,
+			metadata.Language,
+			metadata.Author,
+			metadata.Published,
+			metadata.Modified,
+			entry.ChunksPath,
+			entry.DuplicateOf,
+		}); err != nil {
+			return fmt.Errorf("failed to encode CSV manifest: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to encode CSV manifest: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(s.outputDir, manifestCSVFile), data.Bytes()); err != nil {
+		return fmt.Errorf("failed to write CSV manifest: %w", err)
+	}
+	return nil
+}
+
+// appendManifest appends an entry for a record to the JSON Lines manifest
+// so the manifest can be followed while the crawl is running
+func (s *Storage) appendManifest(record Record) error {
+	if !s.manifest || (record.OutputPath == "" && record.DuplicateOf == "") {
+		return nil
+	}
+
+	line, err := json.Marshal(s.manifestEntry(&record))
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest entry: %w", err)
+	}
+
+	s.manifestMutex.Lock()
+	defer s.manifestMutex.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(s.outputDir, manifestFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open manifest: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to append to manifest: %w", err)
+	}
+	return f.Close()
+}
+
+// manifestEntry converts a record to a manifest entry with an output path
+// relative to the output directory
+func (s *Storage) manifestEntry(record *Record) ManifestEntry {
+	return ManifestEntry{
+		URL:         record.URL,
+		FinalURL:    record.FinalURL,
+		Title:       record.Title,
+		OutputPath:  s.relativePath(record.OutputPath),
+		ChunksPath:  s.relativePath(record.ChunksPath),
+		DuplicateOf: record.DuplicateOf,
+		StatusCode:  record.StatusCode,
+		ContentHash: record.ContentHash,
+		Depth:       record.Depth,
+		ParentURL:   record.ParentURL,
+		FetchedAt:   record.FetchedAt,
+		Metadata:    record.Metadata,
+	}
+}
+
+// relativePath returns a path relative to the output directory
+func (s *Storage) relativePath(path string) string {
+	if path == "" {
+		return ""
+	}
+	if rel, err := filepath.Rel(s.outputDir, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ncecere/bullnose/internal/scraper/content"
+)
+
+const (
+	// stateDir is the directory inside the output directory holding crawl state
+	stateDir = ".bullnose"
+	// recordsFile is the name of the file holding the per-URL records
+	recordsFile = "records.json"
+)
+
+// Record holds what is known about a previously scraped URL
+type Record struct {
+	URL          string    `json:"url"`
+	FinalURL     string    `json:"final_url,omitempty"`
+	Title        string    `json:"title,omitempty"`
+	OutputPath   string    `json:"output_path,omitempty"`
+	ChunksPath   string    `json:"chunks_path,omitempty"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	ContentHash  string    `json:"content_hash,omitempty"`
+	SimHash      string    `json:"simhash,omitempty"`
+	DuplicateOf  string    `json:"duplicate_of,omitempty"`
+	Depth        int       `json:"depth,omitempty"`
+	ParentURL    string    `json:"parent_url,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Links        []string  `json:"links,omitempty"`
+
+	// LocalLinks maps the links made local by rewrite-links to the URLs
+	// they replaced, so they can be resolved again by later runs
+	LocalLinks map[string]string `json:"local_links,omitempty"`
+
+	Metadata *content.Metadata `json:"metadata,omitempty"`
+}
+
+// LoadRecords loads the records persisted by a previous run, if any
+func (s *Storage) LoadRecords() error {
+	data, err := os.ReadFile(s.StatePath(recordsFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read records: %w", err)
+	}
+
+	var records []*Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("failed to parse records: %w", err)
+	}
+
+	s.recordsMutex.Lock()
+	defer s.recordsMutex.Unlock()
+	for _, record := range records {
+		s.records[record.URL] = record
+		if record.OutputPath != "" {
+			s.claims[claimKey(record.OutputPath)] = record.URL
+			if record.DuplicateOf == "" && record.ContentHash != "" {
+				s.contents.add(record.URL, parseSimHash(record))
+			}
+		}
+	}
+	return nil
+}
+
+// SaveRecords persists all records to the output directory
+func (s *Storage) SaveRecords() error {
+	s.recordsMutex.Lock()
+	records := make([]*Record, 0, len(s.records))
+	for _, record := range s.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].URL < records[j].URL
+	})
+	data, err := json.MarshalIndent(records, "", "  ")
+	s.recordsMutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode records: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(s.outputDir, stateDir), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := writeFileAtomic(s.StatePath(recordsFile), data); err != nil {
+		return fmt.Errorf("failed to write records: %w", err)
+	}
+	return nil
+}
+
+// GetRecord returns a copy of the record stored for a URL
+func (s *Storage) GetRecord(url string) (Record, bool) {
+	s.recordsMutex.Lock()
+	defer s.recordsMutex.Unlock()
+	record, ok := s.records[url]
+	if !ok {
+		return Record{}, false
+	}
+	return *record, true
+}
+
+// PutRecord stores the record for a URL, replacing any previous one, and
+// adds it to the manifest
+func (s *Storage) PutRecord(record Record) error {
+	s.recordsMutex.Lock()
+	stored := record
+	if fp, ok := s.contents.fingerprints[record.URL]; ok && record.DuplicateOf == "" {
+		stored.SimHash = formatSimHash(fp)
+	}
+	s.records[record.URL] = &stored
+	s.recordsMutex.Unlock()
+	return s.appendManifest(record)
+}
+
+// Validators returns the ETag and Last-Modified values stored for a URL, or
+// empty strings when the stored copy cannot be reused
+func (s *Storage) Validators(url string) (etag, lastModified string) {
+	if s.force {
+		return "", ""
+	}
+	record, ok := s.GetRecord(url)
+	if !ok {
+		return "", ""
+	}
+	if record.OutputPath != "" {
+		if _, err := os.Stat(record.OutputPath); err != nil {
+			return "", ""
+		}
+	}
+	return record.ETag, record.LastModified
+}
+
+// Touch marks the record for a URL as freshly fetched without changing its
+// content, returning false if no record exists
+func (s *Storage) Touch(url string) (bool, error) {
+	s.recordsMutex.Lock()
+	record, ok := s.records[url]
+	if !ok {
+		s.recordsMutex.Unlock()
+		return false, nil
+	}
+	record.FetchedAt = time.Now().UTC()
+	touched := *record
+	s.recordsMutex.Unlock()
+	return true, s.appendManifest(touched)
+}
+
+// HashContent returns the hex encoded SHA-256 hash of content
+func HashContent(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+// StatePath returns the path of a file in the crawl state directory
+func (s *Storage) StatePath(name string) string {
+	return filepath.Join(s.outputDir, stateDir, name)
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place
+// so readers never observe a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
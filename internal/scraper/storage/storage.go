@@ -4,27 +4,45 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 )
 
 // Storage handles file operations and URL tracking
 type Storage struct {
 	outputDir     string
+	layout        Layout
 	visitedURLs   sync.Map
 	rescrapeAfter time.Duration
 	force         bool
+	records       map[string]*Record
+	claims        map[string]string // output path -> URL owning it
+	recordsMutex  sync.Mutex
+	manifest      bool
+	manifestCSV   bool
+	manifestMutex sync.Mutex
+
+	dedup          bool
+	nearDuplicates bool
+	maxDistance    int
+	contents       *contentIndex // content of each saved page
 }
 
 // New creates a new Storage instance
-func New(outputDir string, rescrapeAfter time.Duration, force bool) *Storage {
+func New(outputDir string, layout Layout, rescrapeAfter time.Duration, force bool) *Storage {
 	return &Storage{
 		outputDir:     outputDir,
+		layout:        layout,
 		rescrapeAfter: rescrapeAfter,
 		force:         force,
+		records:       make(map[string]*Record),
+		claims:        make(map[string]string),
+		contents:      newContentIndex(),
 	}
 }
 
@@ -39,68 +57,99 @@ func (s *Storage) IsVisited(url string) bool {
 	return visited
 }
 
-// ShouldRescrape determines if a file should be rescraped
-func (s *Storage) ShouldRescrape(filepath string) bool {
+// ShouldRescrape determines if a URL should be fetched again based on when
+// it was last scraped
+func (s *Storage) ShouldRescrape(url string) bool {
 	// Always scrape if force is enabled
 	if s.force {
 		return true
 	}
 
-	// Check if file exists
-	info, err := os.Stat(filepath)
-	if os.IsNotExist(err) {
+	// Scrape if we have never seen this URL
+	record, ok := s.GetRecord(url)
+	if !ok {
 		return true
 	}
-	if err != nil {
-		return true
+
+	// Scrape if the previous output has gone missing
+	if record.OutputPath != "" {
+		if _, err := os.Stat(record.OutputPath); err != nil {
+			return true
+		}
 	}
 
 	// Check if enough time has passed since last scrape
-	return time.Since(info.ModTime()) >= s.rescrapeAfter
+	return time.Since(record.FetchedAt) >= s.rescrapeAfter
 }
 
-// SaveContent saves content to a file
-func (s *Storage) SaveContent(domain, title, content string) (string, error) {
-	// Create safe filename from title
-	filename := s.sanitizeFilename(title)
-	if filename == "" {
-		filename = s.hashURL(title) // Use title as URL if no valid filename can be created
+// SaveContent saves the content of a page to a file named according to the
+// configured layout and returns the path written
+func (s *Storage) SaveContent(pageURL, title, content string) (string, error) {
+	outputPath, err := s.OutputPath(pageURL, title)
+	if err != nil {
+		return "", err
 	}
 
-	// Determine output directory based on domain
-	outputDir := filepath.Join(s.outputDir, domain)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create output directory: %w", err)
+	// Remove the file written by a previous run if the page has moved
+	if record, ok := s.GetRecord(pageURL); ok && record.OutputPath != "" &&
+		claimKey(record.OutputPath) != claimKey(outputPath) {
+		s.releasePath(pageURL, record.OutputPath)
+		if err := os.Remove(record.OutputPath); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to remove previous file: %w", err)
+		}
 	}
 
-	// Create markdown file
-	outputPath := filepath.Join(outputDir, filename+".md")
+	// Create the output directory
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
 
-	// Write content to file
-	if err := os.WriteFile(outputPath, []byte(content), 0644); err != nil {
+	// Write content to file without ever leaving a partial file behind
+	if err := writeFileAtomic(outputPath, []byte(content)); err != nil {
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
 
 	return outputPath, nil
 }
 
-// sanitizeFilename creates a safe filename from a string
+// OutputPath returns the path the content of a page is saved to. A page
+// always resolves to the same path, so files that belong next to its
+// markdown can be written before the content is saved.
+func (s *Storage) OutputPath(pageURL, title string) (string, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid page URL: %w", err)
+	}
+	return s.claimPath(pageURL, s.outputPath(u, title)), nil
+}
+
+// sanitizeFilename creates a safe filename from a string, keeping letters
+// and digits of any script and collapsing everything else into dashes
 func (s *Storage) sanitizeFilename(name string) string {
-	// Remove invalid characters
-	name = strings.Map(func(r rune) rune {
-		if r > 127 || strings.ContainsRune(`<>:"/\|?*`, r) {
-			return '-'
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.':
+			if dash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		default:
+			dash = true
 		}
-		return r
-	}, name)
+	}
 
-	// Trim spaces and dashes
-	name = strings.Trim(name, " -")
+	// Trim leading and trailing dots so names never become "." or ".."
+	name = strings.Trim(b.String(), ".-")
 
-	// Replace multiple dashes with single dash
-	name = strings.Join(strings.Fields(name), "-")
+	// Keep names well within file system limits
+	if runes := []rune(name); len(runes) > maxNameLength {
+		name = strings.TrimRight(string(runes[:maxNameLength]), ".-")
+	}
 
-	return strings.ToLower(name)
+	return name
 }
 
 // hashURL creates a hash from a URL string
@@ -0,0 +1,201 @@
+package throttle
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minDelay is the delay applied when a host is first throttled
+const minDelay = 1 * time.Second
+
+// Throttle adapts the request rate of each host to how the host responds.
+// When a host answers 429 Too Many Requests or 503 Service Unavailable its
+// parallelism is halved and its delay doubled; after enough healthy
+// responses the host slowly recovers one step at a time.
+type Throttle struct {
+	maxDelay     time.Duration
+	recoverAfter int
+	maxParallel  func(host string) int
+	hosts        map[string]*host
+	mutex        sync.Mutex
+}
+
+// host is the throttling state of a single host
+type host struct {
+	limit    int // maximum concurrent requests, 0 when not throttled
+	delay    time.Duration
+	active   int
+	nextSlot time.Time
+	slowedAt time.Time
+	healthy  int
+	wake     chan struct{} // closed whenever a waiting request may proceed
+}
+
+// State describes how a host is currently throttled
+type State struct {
+	Parallelism int
+	Delay       time.Duration
+}
+
+// New creates a throttle. maxParallel returns the parallelism a host
+// recovers to, recoverAfter is the number of consecutive healthy responses
+// needed for each recovery step and maxDelay caps the delay between requests.
+func New(maxDelay time.Duration, recoverAfter int, maxParallel func(host string) int) *Throttle {
+	return &Throttle{
+		maxDelay:     maxDelay,
+		recoverAfter: recoverAfter,
+		maxParallel:  maxParallel,
+		hosts:        make(map[string]*host),
+	}
+}
+
+// Acquire blocks until a request to the host may start or ctx is cancelled.
+// Every successful Acquire must be followed by a Release.
+func (t *Throttle) Acquire(ctx context.Context, hostname string) error {
+	for {
+		t.mutex.Lock()
+		h := t.host(hostname)
+		now := time.Now()
+		if h.limit == 0 || (h.active < h.limit && !now.Before(h.nextSlot)) {
+			h.active++
+			h.nextSlot = now.Add(h.delay)
+			t.mutex.Unlock()
+			return nil
+		}
+
+		// Wait for a running request to finish, or for the next slot when
+		// only the delay holds the request back
+		wake := h.wake
+		wait := time.Duration(-1)
+		if h.active < h.limit {
+			wait = h.nextSlot.Sub(now)
+		}
+		t.mutex.Unlock()
+
+		if err := sleep(ctx, wake, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Release records the response status of a request that was started at the
+// given time. It reports whether the host was slowed down because of it.
+// A status code of zero means no response was received.
+func (t *Throttle) Release(hostname string, started time.Time, statusCode int) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	h := t.host(hostname)
+	h.active--
+	defer h.notify()
+
+	switch {
+	case statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable:
+		h.healthy = 0
+		// Requests already in flight when the host was slowed down do not
+		// count towards another slowdown
+		if started.Before(h.slowedAt) {
+			return false
+		}
+		t.slowDown(hostname, h)
+		return true
+	case statusCode > 0 && statusCode < 500:
+		if h.limit == 0 {
+			return false
+		}
+		h.healthy++
+		if h.healthy >= t.recoverAfter {
+			h.healthy = 0
+			t.recover(hostname, h)
+		}
+	}
+	return false
+}
+
+// State returns how the host is currently throttled
+func (t *Throttle) State(hostname string) State {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	h := t.host(hostname)
+	parallelism := h.limit
+	if parallelism == 0 {
+		parallelism = t.maxParallel(hostname)
+	}
+	return State{Parallelism: parallelism, Delay: h.delay}
+}
+
+// slowDown halves the parallelism and doubles the delay of a host
+func (t *Throttle) slowDown(hostname string, h *host) {
+	limit := h.limit
+	if limit == 0 {
+		limit = t.maxParallel(hostname)
+	}
+	h.limit = limit / 2
+	if h.limit < 1 {
+		h.limit = 1
+	}
+
+	h.delay *= 2
+	if h.delay < minDelay {
+		h.delay = minDelay
+	}
+	if h.delay > t.maxDelay {
+		h.delay = t.maxDelay
+	}
+	h.slowedAt = time.Now()
+	h.nextSlot = h.slowedAt.Add(h.delay)
+}
+
+// recover raises the parallelism of a host by one and halves its delay,
+// removing the throttle entirely once both are back to normal
+func (t *Throttle) recover(hostname string, h *host) {
+	if h.limit < t.maxParallel(hostname) {
+		h.limit++
+	}
+	h.delay /= 2
+	if h.delay < minDelay/2 {
+		h.delay = 0
+	}
+	if h.delay == 0 && h.limit >= t.maxParallel(hostname) {
+		h.limit = 0
+	}
+}
+
+// host returns the state of a host, creating it if needed. The caller must
+// hold the mutex.
+func (t *Throttle) host(hostname string) *host {
+	h, ok := t.hosts[hostname]
+	if !ok {
+		h = &host{wake: make(chan struct{})}
+		t.hosts[hostname] = h
+	}
+	return h
+}
+
+// notify wakes all requests waiting for the host. The caller must hold the
+// mutex.
+func (h *host) notify() {
+	close(h.wake)
+	h.wake = make(chan struct{})
+}
+
+// sleep waits until wake is closed, d has passed or ctx is cancelled. A
+// negative d waits for wake or ctx only.
+func sleep(ctx context.Context, wake <-chan struct{}, d time.Duration) error {
+	var timeout <-chan time.Time
+	if d >= 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-wake:
+	case <-timeout:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
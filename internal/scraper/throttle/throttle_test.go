@@ -0,0 +1,76 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRelease(t *testing.T) {
+	const (
+		busy = http.StatusServiceUnavailable
+		ok   = http.StatusOK
+	)
+
+	tests := []struct {
+		name     string
+		statuses []int
+		want     State
+	}{
+		{name: "healthy", statuses: []int{ok, ok}, want: State{Parallelism: 8}},
+		{name: "server error", statuses: []int{http.StatusInternalServerError}, want: State{Parallelism: 8}},
+		{name: "slowed down", statuses: []int{busy}, want: State{Parallelism: 4, Delay: time.Second}},
+		{name: "slowed down twice", statuses: []int{busy, http.StatusTooManyRequests}, want: State{Parallelism: 2, Delay: 2 * time.Second}},
+		{name: "capped", statuses: []int{busy, busy, busy, busy, busy}, want: State{Parallelism: 1, Delay: 10 * time.Second}},
+		{name: "one recovery step", statuses: []int{busy, ok, ok}, want: State{Parallelism: 5, Delay: 500 * time.Millisecond}},
+		{name: "delay recovered", statuses: []int{busy, ok, ok, ok, ok}, want: State{Parallelism: 6}},
+		{name: "fully recovered", statuses: []int{busy, ok, ok, ok, ok, ok, ok, ok, ok}, want: State{Parallelism: 8}},
+		{name: "recovery interrupted", statuses: []int{busy, ok, busy, ok}, want: State{Parallelism: 2, Delay: 2 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttle := New(10*time.Second, 2, func(string) int { return 8 })
+			for _, status := range tt.statuses {
+				throttle.Release("example.com", time.Now(), status)
+			}
+			if got := throttle.State("example.com"); got != tt.want {
+				t.Errorf("State() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseInFlight(t *testing.T) {
+	throttle := New(10*time.Second, 2, func(string) int { return 8 })
+	started := time.Now()
+	if !throttle.Release("example.com", started, http.StatusTooManyRequests) {
+		t.Fatal("Release() did not slow down the host")
+	}
+	// A request started before the slowdown does not slow the host down again
+	if throttle.Release("example.com", started, http.StatusTooManyRequests) {
+		t.Error("Release() slowed down the host for a request already in flight")
+	}
+	if got, want := throttle.State("example.com"), (State{Parallelism: 4, Delay: time.Second}); got != want {
+		t.Errorf("State() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	throttle := New(10*time.Second, 2, func(string) int { return 2 })
+	throttle.Release("example.com", time.Now(), http.StatusServiceUnavailable)
+
+	// The slowed down host allows one request at a time after a delay
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := throttle.Acquire(ctx, "example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire() during the delay = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Other hosts are not affected
+	if err := throttle.Acquire(context.Background(), "example.org"); err != nil {
+		t.Errorf("Acquire() for another host = %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultPorts maps schemes to the port implied when a URL has none
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// URLNormalizer rewrites URLs so that different spellings of the same page
+// are recognized as one
+type URLNormalizer struct {
+	dropParams          []*regexp.Regexp
+	sortQuery           bool
+	ignoreScheme        bool
+	ignoreTrailingSlash bool
+}
+
+// NewURLNormalizer creates a normalizer removing the query parameters whose
+// names match the dropParams glob patterns, case-insensitively. With
+// sortQuery the remaining parameters are sorted by name. ignoreScheme and
+// ignoreTrailingSlash only affect the keys URLs are compared by, since
+// requesting a page under another scheme or path could fail.
+func NewURLNormalizer(dropParams []string, sortQuery, ignoreScheme, ignoreTrailingSlash bool) (*URLNormalizer, error) {
+	n := &URLNormalizer{
+		sortQuery:           sortQuery,
+		ignoreScheme:        ignoreScheme,
+		ignoreTrailingSlash: ignoreTrailingSlash,
+	}
+	for _, pattern := range dropParams {
+		regex, err := regexp.Compile("(?i)" + GlobToRegex(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid query parameter pattern %s: %w", pattern, err)
+		}
+		n.dropParams = append(n.dropParams, regex)
+	}
+	return n, nil
+}
+
+// Normalize returns the form of an absolute URL that is requested and
+// stored: the scheme and host are lowercased, default ports, fragments and
+// dropped query parameters are removed, "." and ".." path segments are
+// resolved and the query is sorted when configured
+func (n *URLNormalizer) Normalize(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return "", fmt.Errorf("not an absolute URL: %s", rawURL)
+	}
+
+	// Resolving the URL against itself removes dot segments
+	u = u.ResolveReference(&url.URL{})
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); port != "" && port == defaultPorts[u.Scheme] {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.RawQuery = n.query(u.RawQuery)
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// Key returns the string URLs are compared by. Besides the normalization,
+// it ignores the scheme and a trailing slash when configured. URLs that
+// cannot be normalized are their own key.
+func (n *URLNormalizer) Key(rawURL string) string {
+	normalized, err := n.Normalize(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if !n.ignoreScheme && !n.ignoreTrailingSlash {
+		return normalized
+	}
+
+	u, err := url.Parse(normalized)
+	if err != nil {
+		return normalized
+	}
+	if n.ignoreTrailingSlash && u.Path != "/" {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+	}
+	if n.ignoreScheme {
+		u.Scheme = ""
+	}
+	return u.String()
+}
+
+// query removes the dropped parameters from a raw query and sorts the rest
+// by name, keeping their original encoding and the order of repeated names
+func (n *URLNormalizer) query(rawQuery string) string {
+	var params []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" || n.dropped(param) {
+			continue
+		}
+		params = append(params, param)
+	}
+	if n.sortQuery {
+		sort.SliceStable(params, func(i, j int) bool {
+			return paramName(params[i]) < paramName(params[j])
+		})
+	}
+	return strings.Join(params, "&")
+}
+
+// dropped reports whether a query parameter matches a dropped pattern
+func (n *URLNormalizer) dropped(param string) bool {
+	name := paramName(param)
+	for _, regex := range n.dropParams {
+		if regex.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// paramName returns the decoded name of a raw query parameter
+func paramName(param string) string {
+	name, _, _ := strings.Cut(param, "=")
+	if unescaped, err := url.QueryUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
+}
@@ -0,0 +1,85 @@
+package utils
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	normalizer, err := NewURLNormalizer([]string{"utm_*", "fbclid"}, true, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "host case", url: "HTTPS://Example.COM/Docs", want: "https://example.com/Docs"},
+		{name: "default port", url: "https://example.com:443/a", want: "https://example.com/a"},
+		{name: "other port", url: "http://example.com:8080/a", want: "http://example.com:8080/a"},
+		{name: "empty path", url: "https://example.com", want: "https://example.com/"},
+		{name: "dot segments", url: "https://example.com/a/./b/../c", want: "https://example.com/a/c"},
+		{name: "fragment", url: "https://example.com/a#section", want: "https://example.com/a"},
+		{name: "empty query", url: "https://example.com/a?", want: "https://example.com/a"},
+		{name: "tracking parameters", url: "https://example.com/a?utm_source=x&id=1&UTM_Medium=y&fbclid=z", want: "https://example.com/a?id=1"},
+		{name: "parameter named like a pattern", url: "https://example.com/a?fbclid2=1", want: "https://example.com/a?fbclid2=1"},
+		{name: "sorted query", url: "https://example.com/a?b=2&a=1&b=1", want: "https://example.com/a?a=1&b=2&b=1"},
+		{name: "query encoding kept", url: "https://example.com/a?q=a%20b&p=c+d", want: "https://example.com/a?p=c+d&q=a%20b"},
+		{name: "encoded parameter name", url: "https://example.com/a?utm%5Fsource=x&id=1", want: "https://example.com/a?id=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizer.Normalize(tt.url)
+			if err != nil {
+				t.Fatalf("Normalize(%q) error: %v", tt.url, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRelative(t *testing.T) {
+	normalizer, err := NewURLNormalizer(nil, false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []string{"/docs", "docs/guide", "mailto:someone@example.com"} {
+		if got, err := normalizer.Normalize(u); err == nil {
+			t.Errorf("Normalize(%q) = %q, want an error", u, got)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name                string
+		ignoreScheme        bool
+		ignoreTrailingSlash bool
+		a, b                string
+		same                bool
+	}{
+		{name: "scheme", a: "http://example.com/a", b: "https://example.com/a", same: false},
+		{name: "scheme ignored", ignoreScheme: true, a: "http://example.com/a", b: "https://example.com/a", same: true},
+		{name: "trailing slash", a: "https://example.com/a/", b: "https://example.com/a", same: false},
+		{name: "trailing slash ignored", ignoreTrailingSlash: true, a: "https://example.com/a/", b: "https://example.com/a", same: true},
+		{name: "root", ignoreTrailingSlash: true, a: "https://example.com/", b: "https://example.com", same: true},
+		{name: "fragment", a: "https://example.com/a#top", b: "https://example.com/a", same: true},
+		{name: "query order", a: "https://example.com/a?x=1&y=2", b: "https://example.com/a?y=2&x=1", same: true},
+		{name: "query values", a: "https://example.com/a?x=1", b: "https://example.com/a?x=2", same: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalizer, err := NewURLNormalizer(nil, true, tt.ignoreScheme, tt.ignoreTrailingSlash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, b := normalizer.Key(tt.a), normalizer.Key(tt.b)
+			if (a == b) != tt.same {
+				t.Errorf("Key(%q) = %q and Key(%q) = %q, want same = %v", tt.a, a, tt.b, b, tt.same)
+			}
+		})
+	}
+}
@@ -7,7 +7,8 @@ import (
 	"strings"
 )
 
-// GetAllowedDomains extracts domains from URLs
+// GetAllowedDomains extracts domains from URLs. Domains are lowercase host
+// names without a port, which is what the collector compares them with.
 func GetAllowedDomains(urls []string) ([]string, error) {
 	domains := make([]string, 0, len(urls))
 	for _, u := range urls {
@@ -15,7 +16,7 @@ func GetAllowedDomains(urls []string) ([]string, error) {
 		if err != nil {
 			return nil, err
 		}
-		domains = append(domains, parsed.Host)
+		domains = append(domains, strings.ToLower(parsed.Hostname()))
 	}
 	return domains, nil
 }
@@ -66,3 +67,48 @@ func CreateURLFilters(patterns []string) ([]*regexp.Regexp, error) {
 	}
 	return filters, nil
 }
+
+// RegexPrefix marks an include pattern as a regular expression rather than
+// a glob pattern
+const RegexPrefix = "re:"
+
+// CreateIncludeFilters creates regex filters from include patterns. Patterns
+// are glob patterns matched against the full URL like ignore patterns, or
+// regular expressions when prefixed with "re:".
+func CreateIncludeFilters(patterns []string) ([]*regexp.Regexp, error) {
+	filters := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		regexPattern := GlobToRegex(pattern)
+		if expr, ok := strings.CutPrefix(pattern, RegexPrefix); ok {
+			regexPattern = expr
+		}
+		regex, err := regexp.Compile(regexPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %s: %w", pattern, err)
+		}
+		filters = append(filters, regex)
+	}
+	return filters, nil
+}
+
+// SeedPathFilter creates a filter matching the URLs under the directory of
+// a seed URL on the same host, over http or https. A last path segment
+// without an extension is taken as a directory, so a seed of /docs/v2 covers
+// /docs/v2/intro but not /docs/v2-beta.
+func SeedPathFilter(seed string) (*regexp.Regexp, error) {
+	parsed, err := url.Parse(seed)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("not an absolute URL: %s", seed)
+	}
+
+	dir := parsed.EscapedPath()
+	if last := dir[strings.LastIndex(dir, "/")+1:]; strings.Contains(last, ".") {
+		dir = dir[:len(dir)-len(last)]
+	}
+	dir = strings.TrimSuffix(dir, "/")
+
+	return regexp.Compile("^https?://" + regexp.QuoteMeta(parsed.Host) + regexp.QuoteMeta(dir) + "(?:[/?].*)?$")
+}